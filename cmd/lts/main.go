@@ -2,18 +2,16 @@ package main
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"go.uber.org/zap"
-	"log"
 	"lts/internal/pkg/app"
 	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata"
 
 	"lts/internal/app/config"
-)
-
-var (
-	//cfgPath = flag.String("config", "", "path to config file")
-	cfgPath = "./configs/config.yaml"
+	"lts/internal/app/logging"
 )
 
 // @title LTS (Leo`s Travel Stories)
@@ -26,37 +24,59 @@ var (
 
 // @license.name AS IS (NO WARRANTY)
 
-// @host localhost:8080
+// @host localhost:8000
 // @schemes  http https
 // @BasePath /api
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+
 func main() {
-	ctx := context.Background()
+	// SIGINT и SIGTERM завершают контекст приложения, и сервер останавливается, дорабатывая начатые запросы
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	zapLogger, err := zap.NewProduction()
+	// считали конфиг: файл, переменные окружения LTS_* и флаги
+	loader, err := config.NewLoader(os.Args[0], os.Args[1:])
+	if errors.Is(err, config.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Fatalf("error at zap.NewProduction: %s", err.Error())
+		logging.Bootstrap().Errorw("[config.NewLoader]", "error", err)
+
+		os.Exit(2)
 	}
 
-	logger := zapLogger.Sugar()
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Bootstrap().Errorw("[loader.Load]", "error", err)
 
-	// считали конфиг
-	flag.Parse()
-	cfg, err := config.Read(ctx, cfgPath)
+		os.Exit(2)
+	}
+
+	logger, level, err := logging.New(cfg.Log)
 	if err != nil {
-		log.Print("[config.Read]")
+		logging.Bootstrap().Errorw("[logging.New]", "error", err)
 
 		os.Exit(2)
 	}
+	// Общий логгер нужен там, где нет логгера запроса, см. logging.FromContext
+	zap.ReplaceGlobals(logger.Desugar())
+	logger.Debugw("config loaded", "path", loader.Path(), "config", cfg.Redacted())
 
 	// Создание приложения
 	application := app.New(ctx, cfg, logger)
+	application.WatchConfig(loader, level)
 
 	// Запуск приложения
 	err = application.Run()
 	if err != nil {
-		log.Print("[application.Run]")
+		logger.Errorw("[application.Run]", "error", err)
+		_ = logger.Sync()
+		stop()
 
-		os.Exit(2)
+		os.Exit(1)
 	}
+	_ = logger.Sync()
 }
@@ -1,82 +1,85 @@
 package main
 
 import (
-	"context"
-	"flag"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"lts/internal/app/config"
+	"lts/internal/app/logging"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"go.uber.org/zap"
 )
 
 const (
 	migrationsPath = "migrations"
 	driver         = "postgres"
-	sslMode        = "disable"
-)
-
-var (
-	//cfgPath = flag.String("config", "", "path to config file")
-	cfgPath = "./configs/config.yaml"
 )
 
 func main() {
-	ctx := context.Background()
-
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	ctx = log.Logger.WithContext(ctx)
+	// считали конфиг
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, config.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logging.Bootstrap().Errorw("[config.Load]", "error", err)
 
-	log.Ctx(ctx).Info().Msg("Starting migrations")
+		os.Exit(2)
+	}
 
-	// считали конфиг
-	flag.Parse()
-	cfg, err := config.Read(ctx, cfgPath)
+	logger, _, err := logging.New(cfg.Log)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("[config.Read]")
+		logging.Bootstrap().Errorw("[logging.New]", "error", err)
 
 		os.Exit(2)
 	}
+	defer logger.Sync()
+
+	logger.Info("Starting migrations")
+	logger.Debugw("config loaded", "config", cfg.Redacted())
 
-	db, err := connect(cfg.PostgresConfig)
+	db, err := connect(cfg.PostgresConfig, logger)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("[connect]")
+		logger.Errorw("[connect]", "error", err)
+		_ = logger.Sync()
 
 		os.Exit(2)
 	}
 
 	// устанавливаем свой логер
-	goose.SetLogger(&gooseLogger{ctx: ctx})
+	goose.SetLogger(&gooseLogger{logger: logger})
 
 	// запускаем миграции
-	log.Ctx(ctx).Info().Msg("Upping migrations")
+	logger.Info("Upping migrations")
 	err = goose.SetDialect(driver)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("[goose.SetDialect]")
+		logger.Errorw("[goose.SetDialect]", "error", err)
+		_ = logger.Sync()
 
 		os.Exit(2)
 	}
 
 	err = goose.Up(db.DB, migrationsPath)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("[goose.Up]")
+		logger.Errorw("[goose.Up]", "error", err)
+		_ = logger.Sync()
+
+		os.Exit(1)
 	}
 
-	log.Ctx(ctx).Info().Msg("DB migration completed")
+	logger.Info("DB migration completed")
 }
 
-// Выполняет подключение к БД
-func connect(repoCfg config.PostgresConfig) (*sqlx.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", repoCfg.Host, repoCfg.Port, repoCfg.User, repoCfg.Password, repoCfg.Name, sslMode)
-	log.Info().Msg(dsn)
+// Выполняет подключение к БД. В лог попадает строка подключения со скрытым паролем
+func connect(repoCfg config.PostgresConfig, logger *zap.SugaredLogger) (*sqlx.DB, error) {
+	logger.Infow("connecting to postgres", "dsn", repoCfg.Redacted().DSN())
 
-	db, err := sqlx.Connect(driver, dsn)
+	db, err := sqlx.Connect(driver, repoCfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("[sqlx.Connect]: %w", err)
 	}
@@ -85,21 +88,26 @@ func connect(repoCfg config.PostgresConfig) (*sqlx.DB, error) {
 
 // Реализация интерфйса goose.Logger
 type gooseLogger struct {
-	ctx context.Context
+	logger *zap.SugaredLogger
 }
 
 func (gl *gooseLogger) Fatal(v ...interface{}) {
-	log.Fatal().Msgf("%v", v)
+	gl.logger.Fatal(v...)
 }
 func (gl *gooseLogger) Fatalf(format string, v ...interface{}) {
-	log.Fatal().Msgf(format, v)
+	gl.logger.Fatal(message(format, v...))
 }
 func (gl *gooseLogger) Print(v ...interface{}) {
-	log.Info().Msgf("%v", v)
+	gl.logger.Info(v...)
 }
 func (gl *gooseLogger) Println(v ...interface{}) {
-	log.Info().Msgf("%v", v)
+	gl.logger.Info(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
 }
 func (gl *gooseLogger) Printf(format string, v ...interface{}) {
-	log.Info().Msgf(format, v)
+	gl.logger.Info(message(format, v...))
+}
+
+// message форматирует сообщение goose без перевода строки в конце
+func message(format string, v ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")
 }
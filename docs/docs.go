@@ -694,7 +694,7 @@ const docTemplate = `{
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
-	Host:             "localhost:8080",
+	Host:             "localhost:8000",
 	BasePath:         "/api",
 	Schemes:          []string{"http", "https"},
 	Title:            "LTS (Leo`s Travel Stories)",
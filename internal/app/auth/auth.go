@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"lts/internal/app/ds"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt учитывает только первые 72 байта пароля
+	maxPasswordLength = 72
+)
+
+// dummyHash - bcrypt-хеш со стоимостью по умолчанию, с которым сравнивается пароль неизвестного пользователя
+const dummyHash = "$2a$10$PFlfpOVabjypa1qawG01Xuses0O//HCBjT6fx2m8QBB8SLIkzUF6u"
+
+var (
+	ErrWeakPassword = fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	ErrLongPassword = fmt.Errorf("password must be at most %d bytes long", maxPasswordLength)
+	ErrNoToken      = errors.New("authorization token is missing")
+)
+
+// Principal - аутентифицированный пользователь текущего запроса.
+// Для просмотра по публичной ссылке вместо пользователя заполняется ShareID.
+// При входе по API-ключу заполняются APIKeyID и Scopes
+type Principal struct {
+	UserID   uuid.UUID
+	Email    string
+	ShareID  uuid.UUID
+	APIKeyID uuid.UUID
+	Scopes   []ds.Scope
+}
+
+// Allows сообщает, есть ли у принципала право scope. Вход по access-токену даёт все права
+func (p Principal) Allows(scope ds.Scope) bool {
+	if p.APIKeyID == uuid.Nil {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal кладёт пользователя в контекст запроса
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext достаёт пользователя из контекста запроса
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// HashPassword хеширует пароль bcrypt с параметрами по умолчанию
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", ErrWeakPassword
+	}
+	if len(password) > maxPasswordLength {
+		return "", ErrLongPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("[bcrypt.GenerateFromPassword]: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сравнивает пароль с хешем за постоянное время
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// CheckNoPassword тратит на проверку столько же времени, сколько CheckPassword, и всегда возвращает false.
+// Вызывается для неизвестного email, чтобы по времени ответа нельзя было узнать, зарегистрирован ли он
+func CheckNoPassword(password string) bool {
+	_ = bcrypt.CompareHashAndPassword([]byte(dummyHash), []byte(password))
+	return false
+}
+
+// NewToken генерирует случайный непрозрачный токен и его хеш для хранения в БД
+func NewToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+
+	_, err = rand.Read(buf)
+	if err != nil {
+		return "", "", fmt.Errorf("[rand.Read]: %w", err)
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken - хеш токена, под которым он хранится в БД
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix отличает API-ключи от JWT в заголовке Authorization
+const APIKeyPrefix = "lts_"
+
+// NewAPIKey генерирует API-ключ и его хеш для хранения в БД
+func NewAPIKey() (key, hash string, err error) {
+	token, _, err := NewToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	key = APIKeyPrefix + token
+	return key, HashToken(key), nil
+}
+
+// IsAPIKey - похож ли токен на API-ключ
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// BearerToken достаёт токен из заголовка "Authorization: Bearer <token>"
+func BearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", ErrNoToken
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"lts/internal/app/config"
+)
+
+const minSecretLength = 32
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+type claims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager выдаёт и проверяет короткоживущие access-токены (JWT, HS256)
+type TokenManager struct {
+	issuer    string
+	ttl       time.Duration
+	activeKey string
+	keys      map[string][]byte
+}
+
+func NewTokenManager(cfg config.AuthConfig) (*TokenManager, error) {
+	if cfg.AccessTTL <= 0 {
+		return nil, errors.New("auth: access_ttl must be positive")
+	}
+
+	keys := make(map[string][]byte, len(cfg.Keys))
+	for _, key := range cfg.Keys {
+		if key.ID == "" {
+			return nil, errors.New("auth: signing key without kid")
+		}
+		if len(key.Secret) < minSecretLength {
+			return nil, fmt.Errorf("auth: secret of key %q must be at least %d bytes", key.ID, minSecretLength)
+		}
+		if _, ok := keys[key.ID]; ok {
+			return nil, fmt.Errorf("auth: duplicate kid %q", key.ID)
+		}
+		keys[key.ID] = []byte(key.Secret)
+	}
+
+	if _, ok := keys[cfg.ActiveKey]; !ok {
+		return nil, fmt.Errorf("auth: active_kid %q is not among keys", cfg.ActiveKey)
+	}
+
+	return &TokenManager{
+		issuer:    cfg.Issuer,
+		ttl:       cfg.AccessTTL,
+		activeKey: cfg.ActiveKey,
+		keys:      keys,
+	}, nil
+}
+
+// Issue подписывает access-токен активным ключом
+func (tm *TokenManager) Issue(principal Principal) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(tm.ttl)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Email: principal.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tm.issuer,
+			Subject:   principal.UserID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			ID:        uuid.NewString(),
+		},
+	})
+	token.Header["kid"] = tm.activeKey
+
+	signed, err := token.SignedString(tm.keys[tm.activeKey])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("[token.SignedString]: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// Parse проверяет подпись (ключом из kid), срок действия и издателя токена
+func (tm *TokenManager) Parse(signed string) (Principal, error) {
+	var c claims
+
+	_, err := jwt.ParseWithClaims(signed, &c, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := tm.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tm.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+
+	return Principal{UserID: userID, Email: c.Email}, nil
+}
@@ -1,51 +1,175 @@
 package config
 
 import (
-	"context"
 	"fmt"
-
-	"github.com/rs/zerolog/log"
-	"github.com/spf13/viper"
+	"strings"
+	"time"
 )
 
 // Config - структура конфигурации.
 // Содержит все конфигурационные данные о сервисе
 type Config struct {
+	HTTP           HTTP           `yaml:"http" mapstructure:"http"`
+	TLS            TLS            `yaml:"tls" mapstructure:"tls"`
+	Storage        Storage        `yaml:"storage" mapstructure:"storage"`
+	CORS           CORS           `yaml:"cors" mapstructure:"cors"`
+	Log            Log            `yaml:"log" mapstructure:"log"`
+	Tracing        Tracing        `yaml:"tracing" mapstructure:"tracing"`
 	PostgresConfig PostgresConfig `yaml:"postgres" mapstructure:"postgres"`
+	AuthConfig     AuthConfig     `yaml:"auth" mapstructure:"auth"`
+	RateLimit      RateLimit      `yaml:"rate_limit" mapstructure:"rate_limit"`
+	Quota          Quota          `yaml:"quota" mapstructure:"quota"`
+	Audit          Audit          `yaml:"audit" mapstructure:"audit"`
+	Trash          Trash          `yaml:"trash" mapstructure:"trash"`
+}
+
+// HTTP - адрес, на котором слушает сервер, и его таймауты.
+// ShutdownTimeout - сколько при остановке ждать завершения начатых запросов
+type HTTP struct {
+	Addr              string        `yaml:"addr" mapstructure:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" mapstructure:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+}
+
+// TLS - сертификат и ключ для HTTPS. Если Enabled выключен, сервер работает по HTTP.
+// MinVersion - 1.2 или 1.3. RedirectAddr - адрес, на котором HTTP-запросы перенаправляются на HTTPS;
+// пустой - не слушать HTTP
+type TLS struct {
+	Enabled      bool   `yaml:"enabled" mapstructure:"enabled"`
+	CertFile     string `yaml:"cert_file" mapstructure:"cert_file"`
+	KeyFile      string `yaml:"key_file" mapstructure:"key_file"`
+	MinVersion   string `yaml:"min_version" mapstructure:"min_version"`
+	RedirectAddr string `yaml:"redirect_addr" mapstructure:"redirect_addr"`
+}
+
+// Storage - где хранятся загруженные изображения. Backend пока только local - каталог Root на диске
+type Storage struct {
+	Backend string `yaml:"backend" mapstructure:"backend"`
+	Root    string `yaml:"root" mapstructure:"root"`
+}
+
+// CORS - политика запросов к API из браузера. AllowedOrigins - точные источники (https://example.com),
+// поддомены (https://*.example.com) или * - любой источник. MaxAge - сколько браузер кэширует ответ на preflight
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" mapstructure:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods" mapstructure:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" mapstructure:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" mapstructure:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" mapstructure:"max_age"`
+}
+
+// Log - уровень (debug, info, warn, error) и формат (json или console) логов
+type Log struct {
+	Level    string      `yaml:"level" mapstructure:"level"`
+	Format   string      `yaml:"format" mapstructure:"format"`
+	Sampling LogSampling `yaml:"sampling" mapstructure:"sampling"`
+}
+
+// LogSampling ограничивает поток одинаковых сообщений: каждую секунду пишутся первые Initial,
+// дальше - каждое Thereafter-е. Initial 0 - писать все сообщения
+type LogSampling struct {
+	Initial    int `yaml:"initial" mapstructure:"initial"`
+	Thereafter int `yaml:"thereafter" mapstructure:"thereafter"`
+}
+
+// Tracing - экспорт трейсов OpenTelemetry. Exporter: none (выключено), otlp (OTLP/HTTP на Endpoint,
+// например localhost:4318) или stdout (в stdout, для локальной отладки). SampleRatio - доля трейсов от 0 до 1
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" mapstructure:"exporter"`
+	Endpoint    string  `yaml:"endpoint" mapstructure:"endpoint"`
+	Insecure    bool    `yaml:"insecure" mapstructure:"insecure"`
+	ServiceName string  `yaml:"service_name" mapstructure:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" mapstructure:"sample_ratio"`
 }
 
-// PostgresConfig - конфигурация для клиента PostgreSQL
+// PostgresConfig - конфигурация для клиента PostgreSQL и пула соединений
 type PostgresConfig struct {
 	Host     string `yaml:"host" mapstructure:"host"`
 	Port     int    `yaml:"port" mapstructure:"port"`
 	User     string `yaml:"user" mapstructure:"user"`
 	Password string `yaml:"pass" mapstructure:"pass"`
 	Name     string `yaml:"name" mapstructure:"name"`
+	SSLMode  string `yaml:"sslmode" mapstructure:"sslmode"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" mapstructure:"conn_max_idle_time"`
+}
+
+// DSN - строка подключения для lib/pq. Значения берутся в кавычки, поэтому пароль может содержать пробелы и кавычки
+func (p PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSN(p.Host), p.Port, quoteDSN(p.User), quoteDSN(p.Password), quoteDSN(p.Name), quoteDSN(p.SSLMode))
+}
+
+// quoteDSN экранирует обратную косую черту и апостроф и заключает значение в апострофы, как требует формат key=value
+func quoteDSN(value string) string {
+	return "'" + dsnEscaper.Replace(value) + "'"
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// AuthConfig - конфигурация выдачи и проверки токенов.
+// Access-токены подписываются ключом ActiveKey, а проверяются любым ключом из Keys,
+// поэтому при ротации старый ключ остаётся в списке, пока не истекут выданные им токены
+type AuthConfig struct {
+	Issuer     string        `yaml:"issuer" mapstructure:"issuer"`
+	AccessTTL  time.Duration `yaml:"access_ttl" mapstructure:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" mapstructure:"refresh_ttl"`
+	ActiveKey  string        `yaml:"active_kid" mapstructure:"active_kid"`
+	Keys       []SigningKey  `yaml:"keys" mapstructure:"keys"`
+	// LegacyOwner - email пользователя, которому при запуске назначаются путешествия,
+	// созданные до появления пользователей
+	LegacyOwner string `yaml:"legacy_owner" mapstructure:"legacy_owner"`
 }
 
-func Read(ctx context.Context, path string) (Config, error) {
-	v := viper.New()
+// SigningKey - секрет HMAC для подписи JWT, kid попадает в заголовок токена
+type SigningKey struct {
+	ID     string `yaml:"kid" mapstructure:"kid"`
+	Secret string `yaml:"secret" mapstructure:"secret"`
+}
 
-	if path == "" {
-		return Config{}, fmt.Errorf("read: empty configuration path")
-	}
+// RateLimit - ограничение частоты запросов. Backend - memory (в памяти процесса)
+// или redis (общий счётчик для нескольких экземпляров сервиса)
+type RateLimit struct {
+	Enabled bool        `yaml:"enabled" mapstructure:"enabled"`
+	Backend string      `yaml:"backend" mapstructure:"backend"`
+	Redis   RedisConfig `yaml:"redis" mapstructure:"redis"`
+	PerIP   Limit       `yaml:"per_ip" mapstructure:"per_ip"`
+	PerUser Limit       `yaml:"per_user" mapstructure:"per_user"`
+}
 
-	v.SetConfigType("yaml")
-	v.SetConfigFile(path)
-	v.WatchConfig()
+// Limit - параметры token bucket: Rate запросов в секунду в среднем, всплеск до Burst
+type Limit struct {
+	Rate  float64 `yaml:"rate" mapstructure:"rate"`
+	Burst int     `yaml:"burst" mapstructure:"burst"`
+}
 
-	err := v.ReadInConfig()
-	if err != nil {
-		return Config{}, fmt.Errorf("read: ReadInConfig error: %w", err)
-	}
+type RedisConfig struct {
+	Addr     string `yaml:"addr" mapstructure:"addr"`
+	Password string `yaml:"password" mapstructure:"password"`
+	DB       int    `yaml:"db" mapstructure:"db"`
+}
 
-	cfg := &Config{}
-	err = v.Unmarshal(cfg)
-	if err != nil {
-		return Config{}, fmt.Errorf("read: Unmarshal error: %w", err)
-	}
+// Quota - ограничения на загружаемые файлы, в байтах.
+// UserStorage считается по всем файлам путешествий, созданных пользователем; 0 - без ограничения
+type Quota struct {
+	UserStorage int64 `yaml:"user_storage" mapstructure:"user_storage"`
+	MaxUpload   int64 `yaml:"max_upload" mapstructure:"max_upload"`
+}
 
-	log.Ctx(ctx).Debug().Interface("cfg", cfg).Msg("config parsed")
+// Audit - журнал изменений. Записи старше Retention удаляются, 0 - хранить без ограничения
+type Audit struct {
+	Retention time.Duration `yaml:"retention" mapstructure:"retention"`
+}
 
-	return *cfg, nil
+// Trash - корзина. Путешествия и места удаляются окончательно вместе с файлами
+// через Retention после удаления в корзину, 0 - хранить без ограничения
+type Trash struct {
+	Retention time.Duration `yaml:"retention" mapstructure:"retention"`
 }
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string
+	}{
+		{"plain", "secret", `host='db' port=5432 user='dev_user' password='secret' dbname='dev_db' sslmode='disable'`},
+		{"empty", "", `host='db' port=5432 user='dev_user' password='' dbname='dev_db' sslmode='disable'`},
+		{"spaces and equals", "a b=c", `host='db' port=5432 user='dev_user' password='a b=c' dbname='dev_db' sslmode='disable'`},
+		{"quote and backslash", `it's\`, `host='db' port=5432 user='dev_user' password='it\'s\\' dbname='dev_db' sslmode='disable'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := PostgresConfig{Host: "db", Port: 5432, User: "dev_user", Password: tt.password, Name: "dev_db", SSLMode: "disable"}
+
+			got := cfg.DSN()
+			if got != tt.want {
+				t.Fatalf("DSN() = %s, want %s", got, tt.want)
+			}
+
+			_, err := pq.NewConnector(got)
+			if err != nil {
+				t.Fatalf("pq.NewConnector() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultPath - файл конфигурации, если не задан флаг --config или LTS_CONFIG
+	DefaultPath = "./configs/config.yaml"
+
+	// EnvPrefix - префикс переменных окружения: LTS_POSTGRES_PASS переопределяет postgres.pass
+	EnvPrefix = "LTS"
+)
+
+// ErrHelp возвращается из Load, если запрошена справка по флагам (--help)
+var ErrHelp = pflag.ErrHelp
+
+// defaults - значения настроек, которых нет ни в файле, ни в окружении, ни во флагах
+var defaults = map[string]any{
+	"http.addr":                   ":8000",
+	"http.read_timeout":           time.Minute,
+	"http.read_header_timeout":    10 * time.Second,
+	"http.write_timeout":          time.Minute,
+	"http.idle_timeout":           2 * time.Minute,
+	"http.shutdown_timeout":       30 * time.Second,
+	"tls.min_version":             "1.2",
+	"storage.backend":             "local",
+	"storage.root":                "./images/travel",
+	"cors.allowed_origins":        []string{"http://localhost:3000"},
+	"cors.allowed_methods":        []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+	"cors.allowed_headers":        []string{"X-Requested-With", "X-HTTP-Method-Override", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Request-ID", "If-Match", "If-None-Match"},
+	"cors.exposed_headers":        []string{"ETag", "Link", "X-Total-Count", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-Quota-Limit", "X-Quota-Used", "X-Request-ID"},
+	"cors.allow_credentials":      true,
+	"cors.max_age":                10 * time.Minute,
+	"log.level":                   "info",
+	"log.format":                  "json",
+	"log.sampling.initial":        100,
+	"log.sampling.thereafter":     100,
+	"tracing.exporter":            "none",
+	"tracing.service_name":        "lts",
+	"tracing.sample_ratio":        1.0,
+	"postgres.port":               5432,
+	"postgres.sslmode":            "disable",
+	"postgres.max_open_conns":     25,
+	"postgres.max_idle_conns":     25,
+	"postgres.conn_max_lifetime":  30 * time.Minute,
+	"postgres.conn_max_idle_time": 5 * time.Minute,
+}
+
+// Loader собирает конфигурацию из файла, переменных окружения LTS_* и флагов командной строки.
+// Флаги важнее переменных окружения, переменные окружения важнее файла.
+// Флаги разбираются один раз в NewLoader, файл перечитывается при каждом вызове Load
+type Loader struct {
+	flags *pflag.FlagSet
+	path  string
+}
+
+// NewLoader разбирает флаги. Путь к файлу задаётся флагом --config или переменной LTS_CONFIG.
+// Каждой настройке соответствуют флаг --<ключ> и переменная LTS_<КЛЮЧ>, например
+// --postgres.host и LTS_POSTGRES_HOST; списки структур (auth.keys) задаются только в файле
+func NewLoader(name string, args []string) (*Loader, error) {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	path := flags.String("config", DefaultPath, "path to config file (env "+EnvPrefix+"_CONFIG)")
+
+	settings(reflect.TypeOf(Config{}), "", func(key string, t reflect.Type) {
+		addFlag(flags, key, t)
+	})
+
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if env, ok := os.LookupEnv(EnvPrefix + "_CONFIG"); ok && !flags.Changed("config") {
+		*path = env
+	}
+	if *path == "" {
+		return nil, fmt.Errorf("load: empty configuration path")
+	}
+
+	return &Loader{flags: flags, path: *path}, nil
+}
+
+// Path - файл конфигурации
+func (l *Loader) Path() string {
+	return l.path
+}
+
+// Load читает и проверяет конфигурацию. Каждый вызов использует новый экземпляр viper,
+// поэтому Load можно вызывать из любой горутины
+func (l *Loader) Load() (Config, error) {
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	var bindErr error
+	settings(reflect.TypeOf(Config{}), "", func(key string, _ reflect.Type) {
+		flag := l.flags.Lookup(key)
+		if flag == nil {
+			return
+		}
+		bindErr = errors.Join(bindErr, v.BindEnv(key), v.BindPFlag(key, flag))
+	})
+	if bindErr != nil {
+		return Config{}, fmt.Errorf("load: bind error: %w", bindErr)
+	}
+
+	v.SetConfigType("yaml")
+	v.SetConfigFile(l.path)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return Config{}, fmt.Errorf("load: ReadInConfig error: %w", err)
+	}
+
+	cfg := Config{}
+	err = v.Unmarshal(&cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("load: Unmarshal error: %w", err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return Config{}, fmt.Errorf("load: invalid configuration:\n%w", err)
+	}
+
+	return cfg, nil
+}
+
+// Watch вызывает fn после каждого изменения файла конфигурации, пока не завершится ctx.
+// Следится каталог файла, поэтому замечается и замена файла переименованием, как это делают
+// редакторы, и подмена симлинка ..data в ConfigMap Kubernetes
+func (l *Loader) Watch(ctx context.Context, fn func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watch: NewWatcher error: %w", err)
+	}
+	defer watcher.Close()
+
+	err = watcher.Add(filepath.Dir(l.path))
+	if err != nil {
+		return fmt.Errorf("watch: Add error: %w", err)
+	}
+
+	path := filepath.Clean(l.path)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			name := filepath.Clean(event.Name)
+			if event.Has(fsnotify.Chmod) || (name != path && !strings.HasPrefix(filepath.Base(name), "..")) {
+				continue
+			}
+			fn()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("watch: %w", err)
+		}
+	}
+}
+
+// Load разбирает флаги args и читает конфигурацию, см. Loader
+func Load(name string, args []string) (Config, error) {
+	loader, err := NewLoader(name, args)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return loader.Load()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// settings обходит поля структуры конфигурации и вызывает fn для каждой настройки
+// с её ключом (postgres.host) и типом. Вложенные структуры обходятся рекурсивно
+func settings(t reflect.Type, prefix string, fn func(key string, t reflect.Type)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			settings(field.Type, key, fn)
+			continue
+		}
+		fn(key, field.Type)
+	}
+}
+
+// addFlag объявляет флаг для настройки, если её тип можно задать флагом и переменной окружения
+func addFlag(flags *pflag.FlagSet, key string, t reflect.Type) {
+	usage := "overrides " + key + " (env " + EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_")) + ")"
+
+	switch {
+	case t == durationType:
+		flags.Duration(key, 0, usage)
+	case t.Kind() == reflect.String:
+		flags.String(key, "", usage)
+	case t.Kind() == reflect.Bool:
+		flags.Bool(key, false, usage)
+	case t.Kind() == reflect.Int:
+		flags.Int(key, 0, usage)
+	case t.Kind() == reflect.Int64:
+		flags.Int64(key, 0, usage)
+	case t.Kind() == reflect.Float64:
+		flags.Float64(key, 0, usage)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		flags.StringSlice(key, nil, usage)
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("http:\n  addr: \":8000\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := NewLoader("lts", []string{"--config", path})
+	if err != nil {
+		t.Fatalf("NewLoader() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- loader.Watch(ctx, func() { changed <- struct{}{} })
+	}()
+
+	// Watch начинает следить не сразу, поэтому файл переписывается, пока изменение не заметят
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	timeout := time.After(5 * time.Second)
+
+wait:
+	for {
+		select {
+		case <-changed:
+			break wait
+		case <-tick.C:
+			if err := os.WriteFile(path, []byte("http:\n  addr: \":8001\"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		case <-timeout:
+			t.Fatal("change of the config file was not noticed")
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not return after the context was cancelled")
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted заменяет секреты в выводе конфигурации
+const redacted = "[REDACTED]"
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные ошибки, по одной на строку
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "http.addr", "must not be empty")
+	check(c.HTTP.ReadTimeout >= 0, "http.read_timeout", "must not be negative")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "http.read_header_timeout", "must not be negative")
+	check(c.HTTP.WriteTimeout >= 0, "http.write_timeout", "must not be negative")
+	check(c.HTTP.IdleTimeout >= 0, "http.idle_timeout", "must not be negative")
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout", "must be positive")
+
+	if c.TLS.Enabled {
+		check(c.TLS.CertFile != "", "tls.cert_file", "is required when tls is enabled")
+		check(c.TLS.KeyFile != "", "tls.key_file", "is required when tls is enabled")
+		check(c.TLS.MinVersion == "1.2" || c.TLS.MinVersion == "1.3", "tls.min_version", "must be 1.2 or 1.3, got %q", c.TLS.MinVersion)
+		check(c.TLS.RedirectAddr != c.HTTP.Addr, "tls.redirect_addr", "must differ from http.addr")
+	}
+
+	check(c.Storage.Backend == "local", "storage.backend", "unknown backend %q, supported: local", c.Storage.Backend)
+	check(c.Storage.Root != "", "storage.root", "must not be empty")
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(validOrigin(origin), "cors.allowed_origins", "%q is not an origin like https://example.com, https://*.example.com or *", origin)
+	}
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"), "cors.allowed_origins", "* is not allowed with cors.allow_credentials, list the origins explicitly")
+	check(len(c.CORS.AllowedMethods) > 0, "cors.allowed_methods", "must not be empty")
+	for _, method := range c.CORS.AllowedMethods {
+		check(method != "" && method == strings.ToUpper(method), "cors.allowed_methods", "%q must be an upper-case method name", method)
+	}
+	check(c.CORS.MaxAge >= 0, "cors.max_age", "must not be negative")
+
+	_, err := zapcore.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level", "unknown level %q", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "console", "log.format", "must be json or console, got %q", c.Log.Format)
+	check(c.Log.Sampling.Initial >= 0, "log.sampling.initial", "must not be negative")
+	check(c.Log.Sampling.Thereafter >= 0, "log.sampling.thereafter", "must not be negative")
+
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "otlp" || c.Tracing.Exporter == "stdout", "tracing.exporter", "must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint", "is required for the otlp exporter")
+	check(c.Tracing.ServiceName != "", "tracing.service_name", "must not be empty")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1")
+
+	if c.AuthConfig.LegacyOwner != "" {
+		_, err := mail.ParseAddress(c.AuthConfig.LegacyOwner)
+		check(err == nil, "auth.legacy_owner", "%q is not an email", c.AuthConfig.LegacyOwner)
+	}
+
+	check(c.PostgresConfig.Host != "", "postgres.host", "must not be empty")
+	check(c.PostgresConfig.Port > 0 && c.PostgresConfig.Port < 65536, "postgres.port", "must be between 1 and 65535")
+	check(c.PostgresConfig.User != "", "postgres.user", "must not be empty")
+	check(c.PostgresConfig.Name != "", "postgres.name", "must not be empty")
+	check(slices.Contains(sslModes, c.PostgresConfig.SSLMode), "postgres.sslmode", "unknown mode %q", c.PostgresConfig.SSLMode)
+	check(c.PostgresConfig.MaxOpenConns >= 0, "postgres.max_open_conns", "must not be negative")
+	check(c.PostgresConfig.MaxIdleConns >= 0, "postgres.max_idle_conns", "must not be negative")
+	check(c.PostgresConfig.ConnMaxLifetime >= 0, "postgres.conn_max_lifetime", "must not be negative")
+	check(c.PostgresConfig.ConnMaxIdleTime >= 0, "postgres.conn_max_idle_time", "must not be negative")
+
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.Backend == "" || c.RateLimit.Backend == "memory" || c.RateLimit.Backend == "redis", "rate_limit.backend", "must be memory or redis, got %q", c.RateLimit.Backend)
+		check(c.RateLimit.PerIP.Rate > 0 && c.RateLimit.PerIP.Burst > 0, "rate_limit.per_ip", "rate and burst must be positive")
+		check(c.RateLimit.PerUser.Rate > 0 && c.RateLimit.PerUser.Burst > 0, "rate_limit.per_user", "rate and burst must be positive")
+	}
+
+	check(c.Quota.UserStorage >= 0, "quota.user_storage", "must not be negative")
+	check(c.Quota.MaxUpload >= 0, "quota.max_upload", "must not be negative")
+	check(c.Audit.Retention >= 0, "audit.retention", "must not be negative")
+	check(c.Trash.Retention >= 0, "trash.retention", "must not be negative")
+
+	return errors.Join(errs...)
+}
+
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+
+	// Поддомены: https://*.example.com проверяется как https://x.example.com
+	origin = strings.Replace(origin, "://*.", "://x.", 1)
+	if strings.Contains(origin, "*") {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" && u.RawQuery == ""
+}
+
+// Redacted возвращает копию конфигурации, в которой пароли и ключи подписи заменены, для вывода в лог
+func (c Config) Redacted() Config {
+	c.PostgresConfig = c.PostgresConfig.Redacted()
+	if c.RateLimit.Redis.Password != "" {
+		c.RateLimit.Redis.Password = redacted
+	}
+
+	keys := make([]SigningKey, len(c.AuthConfig.Keys))
+	for i, key := range c.AuthConfig.Keys {
+		keys[i] = SigningKey{ID: key.ID, Secret: redacted}
+	}
+	c.AuthConfig.Keys = keys
+
+	return c
+}
+
+// Redacted возвращает копию настроек подключения со скрытым паролем, например для вывода DSN в лог
+func (p PostgresConfig) Redacted() PostgresConfig {
+	if p.Password != "" {
+		p.Password = redacted
+	}
+	return p
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig - минимальная конфигурация, которая проходит Validate
+func validConfig() Config {
+	return Config{
+		HTTP:    HTTP{Addr: ":8000", ShutdownTimeout: 30 * time.Second},
+		Storage: Storage{Backend: "local", Root: "./images/travel"},
+		CORS: CORS{
+			AllowedOrigins:   []string{"http://localhost:3000"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowCredentials: true,
+		},
+		Log:            Log{Level: "info", Format: "json"},
+		Tracing:        Tracing{Exporter: "none", ServiceName: "lts", SampleRatio: 1},
+		PostgresConfig: PostgresConfig{Host: "db", Port: 5432, User: "dev_user", Name: "dev_db", SSLMode: "disable"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		keys   []string // настройки, которые должны попасть в ошибку; пусто - конфигурация валидна
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"empty addr", func(c *Config) { c.HTTP.Addr = "" }, []string{"http.addr"}},
+		{"negative timeout", func(c *Config) { c.HTTP.ReadTimeout = -time.Second }, []string{"http.read_timeout"}},
+		{"zero shutdown timeout", func(c *Config) { c.HTTP.ShutdownTimeout = 0 }, []string{"http.shutdown_timeout"}},
+		{
+			name:   "tls without files",
+			modify: func(c *Config) { c.TLS = TLS{Enabled: true, MinVersion: "1.1", RedirectAddr: ":8000"} },
+			keys:   []string{"tls.cert_file", "tls.key_file", "tls.min_version", "tls.redirect_addr"},
+		},
+		{"tls disabled is not checked", func(c *Config) { c.TLS = TLS{MinVersion: "1.0"} }, nil},
+		{"unknown storage", func(c *Config) { c.Storage.Backend = "s3" }, []string{"storage.backend"}},
+		{"origin with path", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://example.com/app"} }, []string{"cors.allowed_origins"}},
+		{"wildcard with credentials", func(c *Config) { c.CORS.AllowedOrigins = []string{"*"} }, []string{"cors.allowed_origins"}},
+		{
+			name: "wildcard without credentials",
+			modify: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"*"}
+				c.CORS.AllowCredentials = false
+			},
+		},
+		{"subdomain with credentials", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://*.example.com"} }, nil},
+		{"no methods", func(c *Config) { c.CORS.AllowedMethods = nil }, []string{"cors.allowed_methods"}},
+		{"lower-case method", func(c *Config) { c.CORS.AllowedMethods = []string{"get"} }, []string{"cors.allowed_methods"}},
+		{"negative max age", func(c *Config) { c.CORS.MaxAge = -time.Second }, []string{"cors.max_age"}},
+		{"unknown log level", func(c *Config) { c.Log.Level = "verbose" }, []string{"log.level"}},
+		{"unknown log format", func(c *Config) { c.Log.Format = "text" }, []string{"log.format"}},
+		{"otlp without endpoint", func(c *Config) { c.Tracing.Exporter = "otlp" }, []string{"tracing.endpoint"}},
+		{"sample ratio above one", func(c *Config) { c.Tracing.SampleRatio = 1.5 }, []string{"tracing.sample_ratio"}},
+		{"legacy owner", func(c *Config) { c.AuthConfig.LegacyOwner = "me@example.com" }, nil},
+		{"legacy owner is not an email", func(c *Config) { c.AuthConfig.LegacyOwner = "me" }, []string{"auth.legacy_owner"}},
+		{"port out of range", func(c *Config) { c.PostgresConfig.Port = 70000 }, []string{"postgres.port"}},
+		{"unknown sslmode", func(c *Config) { c.PostgresConfig.SSLMode = "on" }, []string{"postgres.sslmode"}},
+		{"rate limit disabled is not checked", func(c *Config) { c.RateLimit.Backend = "memcached" }, nil},
+		{
+			name: "rate limit enabled",
+			modify: func(c *Config) {
+				c.RateLimit.Enabled = true
+				c.RateLimit.Backend = "memcached"
+			},
+			keys: []string{"rate_limit.backend", "rate_limit.per_ip", "rate_limit.per_user"},
+		},
+		{"negative retention", func(c *Config) { c.Trash.Retention = -time.Hour }, []string{"trash.retention"}},
+		{
+			name: "all errors are reported",
+			modify: func(c *Config) {
+				c.HTTP.Addr = ""
+				c.PostgresConfig.Host = ""
+				c.Quota.MaxUpload = -1
+			},
+			keys: []string{"http.addr", "postgres.host", "quota.max_upload"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if len(tt.keys) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Validate() = nil, want errors for %v", tt.keys)
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tt.keys) {
+				t.Fatalf("Validate() =\n%v\nwant %d errors for %v", err, len(tt.keys), tt.keys)
+			}
+			for i, key := range tt.keys {
+				if !strings.HasPrefix(lines[i], key+": ") {
+					t.Fatalf("error %d = %q, want for %s", i, lines[i], key)
+				}
+			}
+		})
+	}
+}
+
+func TestValidOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"*", true},
+		{"https://example.com", true},
+		{"http://localhost:3000", true},
+		{"https://*.example.com", true},
+		{"https://*.example.com:8443", true},
+		{"example.com", false},
+		{"ftp://example.com", false},
+		{"https://", false},
+		{"https://example.com/", false},
+		{"https://example.com?a=b", false},
+		{"https://*example.com", false},
+		{"https://app.*.example.com", false},
+		{"https://*.*.example.com", false},
+		{"*.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := validOrigin(tt.origin); got != tt.want {
+				t.Fatalf("validOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package ds
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Scope - право, выдаваемое API-ключу
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"  // GET-запросы
+	ScopeWrite Scope = "write" // создание, изменение и удаление
+	ScopeMedia Scope = "media" // загрузка изображений и GPX
+)
+
+var Scopes = []Scope{ScopeRead, ScopeWrite, ScopeMedia}
+
+func (s Scope) Valid() bool {
+	for _, scope := range Scopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey - именованный ключ для скриптов и интеграций.
+// Key отдаётся только при создании, в БД хранится его хеш и Prefix для узнавания в списке
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []Scope    `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
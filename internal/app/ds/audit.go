@@ -0,0 +1,46 @@
+package ds
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore" // восстановление из корзины
+)
+
+// AuditEntity - тип изменённой сущности. Загрузка файлов пишется как media
+// с id путешествия или места, к которому относится файл
+type AuditEntity string
+
+const (
+	AuditTravel  AuditEntity = "travel"
+	AuditPlace   AuditEntity = "place"
+	AuditExpense AuditEntity = "expense"
+	AuditLeg     AuditEntity = "leg"
+	AuditTrack   AuditEntity = "track"
+	AuditMedia   AuditEntity = "media"
+)
+
+// AuditEntry - запись журнала изменений. Before и After содержат только изменившиеся поля,
+// при создании Before пуст, при удалении пуст After
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	TravelID   uuid.UUID       `json:"travel_id"`
+	ActorID    *uuid.UUID      `json:"actor_id,omitempty"`
+	ActorEmail string          `json:"actor_email,omitempty"`
+	APIKeyID   *uuid.UUID      `json:"api_key_id,omitempty"`
+	Action     AuditAction     `json:"action"`
+	EntityType AuditEntity     `json:"entity_type"`
+	EntityID   uuid.UUID       `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package ds
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Comment - комментарий к путешествию (PlaceID пуст) или к месту.
+// Ответы бывают только на комментарии верхнего уровня и приходят вместе с ними в Replies.
+// У удалённого комментария стирается текст, но он остаётся в ветке, чтобы не терять ответы
+type Comment struct {
+	ID          uuid.UUID  `json:"id"`
+	TravelID    uuid.UUID  `json:"travel_id"`
+	PlaceID     *uuid.UUID `json:"place_id,omitempty"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	AuthorID    *uuid.UUID `json:"author_id,omitempty"`
+	AuthorEmail string     `json:"author_email,omitempty"`
+	Body        string     `json:"body"`
+	Deleted     bool       `json:"deleted"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Reactions   []Reaction `json:"reactions"`
+	Replies     []Comment  `json:"replies,omitempty"`
+}
+
+// Reaction - эмодзи под комментарием: сколько раз его поставили и есть ли среди них текущий пользователь
+type Reaction struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+type CommentRequest struct {
+	Body     string     `json:"body"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+}
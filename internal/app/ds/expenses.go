@@ -2,11 +2,38 @@ package ds
 
 import "github.com/google/uuid"
 
+// SplitType - правило разделения расхода между участниками
+type SplitType string
+
+const (
+	// SplitEqual - поровну между участниками из Shares (или между всеми участниками путешествия, если Shares пуст)
+	SplitEqual SplitType = "equal"
+	// SplitShares - пропорционально долям из Shares
+	SplitShares SplitType = "shares"
+	// SplitExact - точные суммы из Shares, в сумме равные Total
+	SplitExact SplitType = "exact"
+)
+
 type Expense struct {
-	ID            uuid.UUID `json:"id"`
-	Road          int       `json:"road"`
-	Residence     int       `json:"residence"`
-	Food          int       `json:"food"`
-	Entertainment int       `json:"entertainment"`
-	Other         int       `json:"other"`
+	ID            uuid.UUID      `json:"id"`
+	Road          int            `json:"road"`
+	Residence     int            `json:"residence"`
+	Food          int            `json:"food"`
+	Entertainment int            `json:"entertainment"`
+	Other         int            `json:"other"`
+	Payer         uuid.UUID      `json:"payer"`
+	SplitType     SplitType      `json:"split_type"`
+	Shares        []ExpenseShare `json:"shares"`
+}
+
+// ExpenseShare - доля участника в расходе.
+// Для SplitShares это вес, для SplitExact - сумма, для SplitEqual значение игнорируется
+type ExpenseShare struct {
+	ParticipantID uuid.UUID `json:"participant_id"`
+	Value         int       `json:"value"`
+}
+
+// Total - общая сумма расхода по всем категориям
+func (e Expense) Total() int {
+	return e.Road + e.Residence + e.Food + e.Entertainment + e.Other
 }
@@ -0,0 +1,12 @@
+package ds
+
+import "github.com/google/uuid"
+
+// StoredFile - загруженный файл путешествия. Path задан относительно корня хранилища,
+// PlaceID пуст у превью путешествия. По сумме Size считается квота на файлы
+type StoredFile struct {
+	Path     string
+	TravelID uuid.UUID
+	PlaceID  uuid.UUID
+	Size     int64
+}
@@ -0,0 +1,21 @@
+package ds
+
+import "lts/internal/app/buildinfo"
+
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// Readiness - результат проверок готовности: имя проверки -> ok или текст ошибки
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Version - сборка сервиса и версия схемы БД. SchemaVersion не заполняется, если БД недоступна
+type Version struct {
+	buildinfo.Info
+	SchemaVersion         *int64 `json:"schema_version,omitempty"`
+	ExpectedSchemaVersion int64  `json:"expected_schema_version"`
+}
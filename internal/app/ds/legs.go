@@ -0,0 +1,110 @@
+package ds
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransportMode - способ перемещения между местами
+type TransportMode string
+
+const (
+	ModeFlight TransportMode = "flight"
+	ModeTrain  TransportMode = "train"
+	ModeBus    TransportMode = "bus"
+	ModeCar    TransportMode = "car"
+	ModeFerry  TransportMode = "ferry"
+	ModeBike   TransportMode = "bike"
+	ModeWalk   TransportMode = "walk"
+	ModeOther  TransportMode = "other"
+)
+
+func (m TransportMode) Valid() bool {
+	switch m {
+	case ModeFlight, ModeTrain, ModeBus, ModeCar, ModeFerry, ModeBike, ModeWalk, ModeOther:
+		return true
+	}
+	return false
+}
+
+// Leg - перемещение между двумя соседними местами путешествия.
+// Время хранится в UTC, а DepartureTZ/ArrivalTZ (имена IANA) задают, в каком поясе его показывать
+type Leg struct {
+	ID          uuid.UUID     `json:"id"`
+	TravelID    uuid.UUID     `json:"travel_id"`
+	FromPlace   uuid.UUID     `json:"from_place"`
+	ToPlace     uuid.UUID     `json:"to_place"`
+	Mode        TransportMode `json:"mode"`
+	Carrier     string        `json:"carrier,omitempty"`
+	DepartureAt *time.Time    `json:"departure_at,omitempty"`
+	DepartureTZ string        `json:"departure_tz,omitempty"`
+	ArrivalAt   *time.Time    `json:"arrival_at,omitempty"`
+	ArrivalTZ   string        `json:"arrival_tz,omitempty"`
+	BookingRef  string        `json:"booking_ref,omitempty"`
+	Distance    *float64      `json:"distance,omitempty"` // метры
+	Expenses    uuid.UUID     `json:"expenses"`
+}
+
+type FullLeg struct {
+	ID          uuid.UUID     `json:"id"`
+	FromPlace   uuid.UUID     `json:"from_place"`
+	ToPlace     uuid.UUID     `json:"to_place"`
+	Mode        TransportMode `json:"mode"`
+	Carrier     string        `json:"carrier,omitempty"`
+	DepartureAt *time.Time    `json:"departure_at,omitempty"`
+	DepartureTZ string        `json:"departure_tz,omitempty"`
+	ArrivalAt   *time.Time    `json:"arrival_at,omitempty"`
+	ArrivalTZ   string        `json:"arrival_tz,omitempty"`
+	BookingRef  string        `json:"booking_ref,omitempty"`
+	Distance    *float64      `json:"distance,omitempty"`
+	Expenses    *Expense      `json:"expenses"`
+}
+
+// Validate проверяет вид транспорта, часовые пояса и порядок времени
+func (l Leg) Validate() error {
+	if !l.Mode.Valid() {
+		return fmt.Errorf("unknown transport mode %q", l.Mode)
+	}
+	if l.FromPlace == uuid.Nil || l.ToPlace == uuid.Nil {
+		return errors.New("from_place and to_place are required")
+	}
+	if l.FromPlace == l.ToPlace {
+		return errors.New("from_place and to_place must differ")
+	}
+	for _, tz := range []string{l.DepartureTZ, l.ArrivalTZ} {
+		if tz == "" {
+			continue
+		}
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("unknown time zone %q", tz)
+		}
+	}
+	if l.DepartureAt != nil && l.ArrivalAt != nil && l.ArrivalAt.Before(*l.DepartureAt) {
+		return errors.New("arrival must not be earlier than departure")
+	}
+	if l.Distance != nil && *l.Distance < 0 {
+		return errors.New("distance must not be negative")
+	}
+	return nil
+}
+
+// Localize переводит время отправления и прибытия в их часовые пояса
+func (l *Leg) Localize() {
+	l.DepartureAt = inZone(l.DepartureAt, l.DepartureTZ)
+	l.ArrivalAt = inZone(l.ArrivalAt, l.ArrivalTZ)
+}
+
+func inZone(t *time.Time, tz string) *time.Time {
+	if t == nil || tz == "" {
+		return t
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return t
+	}
+	local := t.In(loc)
+	return &local
+}
@@ -0,0 +1,68 @@
+package ds
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Role - роль участника путешествия. Каждая следующая роль включает права предыдущей
+type Role string
+
+const (
+	RoleViewer Role = "viewer" // читает путешествие и всё, что в нём
+	RoleEditor Role = "editor" // добавляет и изменяет места, перемещения, расходы
+	RoleOwner  Role = "owner"  // удаляет путешествие и управляет участниками
+)
+
+// Roles - все роли по возрастанию прав
+var Roles = []Role{RoleViewer, RoleEditor, RoleOwner}
+
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r Role) Valid() bool {
+	return r.rank() >= 0
+}
+
+// Allows сообщает, хватает ли роли r прав роли required
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && r.rank() >= required.rank()
+}
+
+// Member - пользователь с доступом к путешествию
+type Member struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Invite - приглашение в путешествие. Token отдаётся только при создании, в БД хранится его хеш
+type Invite struct {
+	ID        uuid.UUID `json:"id"`
+	TravelID  uuid.UUID `json:"travel_id"`
+	Email     string    `json:"email"`
+	Role      Role      `json:"role"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type InviteRequest struct {
+	Email string `json:"email"`
+	Role  Role   `json:"role"`
+}
+
+type AcceptInvite struct {
+	Token string `json:"token"`
+}
+
+type RoleUpdate struct {
+	Role Role `json:"role"`
+}
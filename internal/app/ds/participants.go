@@ -0,0 +1,31 @@
+package ds
+
+import "github.com/google/uuid"
+
+type Participant struct {
+	ID       uuid.UUID `json:"id"`
+	TravelID uuid.UUID `json:"travel_id"`
+	Name     string    `json:"name"`
+}
+
+// Balance - итог участника по всем расходам путешествия.
+// Net > 0 - участнику должны, Net < 0 - должен он
+type Balance struct {
+	ParticipantID uuid.UUID `json:"participant_id"`
+	Name          string    `json:"name"`
+	Paid          int       `json:"paid"`
+	Owed          int       `json:"owed"`
+	Net           int       `json:"net"`
+}
+
+// Transfer - перевод, который нужно сделать для взаиморасчёта
+type Transfer struct {
+	From   uuid.UUID `json:"from"`
+	To     uuid.UUID `json:"to"`
+	Amount int       `json:"amount"`
+}
+
+type TravelBalances struct {
+	Balances  []Balance  `json:"balances"`
+	Transfers []Transfer `json:"transfers"`
+}
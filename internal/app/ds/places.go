@@ -1,26 +1,73 @@
 package ds
 
 import (
+	"errors"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"sort"
 )
 
 type FullPlace struct {
-	ID       uuid.UUID      `json:"id"`
-	Name     string         `json:"name"`
-	Story    string         `json:"story"`
-	Date     DateOnlyTime   `json:"date"`
-	Images   pq.StringArray `json:"images"`
-	Expenses *Expense       `json:"expenses"`
-	Preview  string         `json:"preview"`
+	ID          uuid.UUID      `json:"id"`
+	Name        string         `json:"name"`
+	Story       string         `json:"story"`
+	Date        DateOnlyTime   `json:"date"`
+	Images      pq.StringArray `json:"images"`
+	Expenses    *Expense       `json:"expenses"`
+	Preview     string         `json:"preview"`
+	Latitude    *float64       `json:"latitude,omitempty"`
+	Longitude   *float64       `json:"longitude,omitempty"`
+	Address     string         `json:"address,omitempty"`
+	CountryCode string         `json:"country_code,omitempty"`
 }
 
 type Place struct {
-	ID       uuid.UUID      `json:"id"`
-	Name     string         `json:"name"`
-	Story    string         `json:"story"`
-	Date     DateOnlyTime   `json:"date"`
-	Images   pq.StringArray `json:"images"`
-	Expenses uuid.UUID      `json:"expenses"`
-	Preview  string         `json:"preview"`
+	ID          uuid.UUID      `json:"id"`
+	Name        string         `json:"name"`
+	Story       string         `json:"story"`
+	Date        DateOnlyTime   `json:"date"`
+	Images      pq.StringArray `json:"images"`
+	Expenses    uuid.UUID      `json:"expenses"`
+	Preview     string         `json:"preview"`
+	Latitude    *float64       `json:"latitude,omitempty"`
+	Longitude   *float64       `json:"longitude,omitempty"`
+	Address     string         `json:"address,omitempty"`
+	CountryCode string         `json:"country_code,omitempty"`
+}
+
+// HasLocation - заданы ли у места координаты
+func (p Place) HasLocation() bool {
+	return p.Latitude != nil && p.Longitude != nil
+}
+
+// ValidateLocation проверяет координаты и код страны (ISO 3166-1 alpha-2)
+func (p Place) ValidateLocation() error {
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	if p.Latitude != nil && (*p.Latitude < -90 || *p.Latitude > 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if p.Longitude != nil && (*p.Longitude < -180 || *p.Longitude > 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if p.CountryCode != "" {
+		if len(p.CountryCode) != 2 {
+			return errors.New("country code must be an ISO 3166-1 alpha-2 code")
+		}
+		for _, c := range p.CountryCode {
+			if c < 'A' || c > 'Z' {
+				return errors.New("country code must be an ISO 3166-1 alpha-2 code")
+			}
+		}
+	}
+	return nil
+}
+
+// SortByVisit упорядочивает места по дате посещения,
+// при совпадении дат сохраняется порядок добавления в путешествие
+func SortByVisit(places []Place) {
+	sort.SliceStable(places, func(i, j int) bool {
+		return places[i].Date.Before(places[j].Date.Time)
+	})
 }
@@ -0,0 +1,34 @@
+package ds
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// RevisionEntity - сущность, для текста которой хранятся ревизии:
+// описание путешествия или рассказ о месте
+type RevisionEntity string
+
+const (
+	RevisionTravel RevisionEntity = "travel"
+	RevisionPlace  RevisionEntity = "place"
+)
+
+// Revision - сохранённая версия текста. Diff - изменения относительно предыдущей ревизии в формате unified diff
+type Revision struct {
+	ID          int64          `json:"id"`
+	EntityType  RevisionEntity `json:"entity_type"`
+	EntityID    uuid.UUID      `json:"entity_id"`
+	AuthorID    *uuid.UUID     `json:"author_id,omitempty"`
+	AuthorEmail string         `json:"author_email,omitempty"`
+	Body        string         `json:"body"`
+	Diff        string         `json:"diff"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// RevisionDiff - разница между двумя ревизиями
+type RevisionDiff struct {
+	From int64  `json:"from"`
+	To   int64  `json:"to"`
+	Diff string `json:"diff"`
+}
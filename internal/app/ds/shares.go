@@ -0,0 +1,51 @@
+package ds
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ShareLink - публичная ссылка на просмотр путешествия без аккаунта.
+// Token отдаётся только при создании, в БД хранится его хеш
+type ShareLink struct {
+	ID            uuid.UUID  `json:"id"`
+	TravelID      uuid.UUID  `json:"travel_id"`
+	Token         string     `json:"token,omitempty"`
+	HideExpenses  bool       `json:"hide_expenses"`
+	StripLocation bool       `json:"strip_location"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+type ShareRequest struct {
+	HideExpenses  bool       `json:"hide_expenses"`
+	StripLocation bool       `json:"strip_location"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// Redact убирает из путешествия то, что ссылка не должна показывать
+func (s ShareLink) Redact(travel *FullTravel) {
+	for i := range travel.Places {
+		place := &travel.Places[i]
+		if s.HideExpenses {
+			place.Expenses = nil
+		}
+		if s.StripLocation {
+			place.Latitude, place.Longitude = nil, nil
+			place.Address, place.CountryCode = "", ""
+		}
+	}
+
+	for i := range travel.Legs {
+		leg := &travel.Legs[i]
+		// Номер брони позволяет управлять чужим бронированием, по ссылке он не показывается никогда
+		leg.BookingRef = ""
+		if s.HideExpenses {
+			leg.Expenses = nil
+		}
+		// Расстояние между местами выдаёт их координаты не хуже самих координат
+		if s.StripLocation {
+			leg.Distance = nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ds
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func sharedTravel() FullTravel {
+	lat, lon, distance := 55.75, 37.62, 634000.0
+	return FullTravel{
+		ID:   uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Name: "Summer",
+		Places: []FullPlace{{
+			Name:        "Moscow",
+			Expenses:    &Expense{Food: 100},
+			Latitude:    &lat,
+			Longitude:   &lon,
+			Address:     "Red square",
+			CountryCode: "RU",
+		}},
+		Legs: []FullLeg{{
+			Mode:       "train",
+			BookingRef: "PNR123",
+			Distance:   &distance,
+			Expenses:   &Expense{Road: 50},
+		}},
+	}
+}
+
+func TestShareLinkRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		link  ShareLink
+		apply func(travel *FullTravel)
+	}{
+		{
+			name: "booking ref is always hidden",
+			link: ShareLink{},
+			apply: func(travel *FullTravel) {
+				travel.Legs[0].BookingRef = ""
+			},
+		},
+		{
+			name: "hide expenses",
+			link: ShareLink{HideExpenses: true},
+			apply: func(travel *FullTravel) {
+				travel.Places[0].Expenses = nil
+				travel.Legs[0].Expenses = nil
+				travel.Legs[0].BookingRef = ""
+			},
+		},
+		{
+			name: "strip location",
+			link: ShareLink{StripLocation: true},
+			apply: func(travel *FullTravel) {
+				travel.Places[0].Latitude, travel.Places[0].Longitude = nil, nil
+				travel.Places[0].Address, travel.Places[0].CountryCode = "", ""
+				travel.Legs[0].BookingRef = ""
+				travel.Legs[0].Distance = nil
+			},
+		},
+		{
+			name: "hide expenses and strip location",
+			link: ShareLink{HideExpenses: true, StripLocation: true},
+			apply: func(travel *FullTravel) {
+				travel.Places[0].Expenses = nil
+				travel.Places[0].Latitude, travel.Places[0].Longitude = nil, nil
+				travel.Places[0].Address, travel.Places[0].CountryCode = "", ""
+				travel.Legs[0].Expenses = nil
+				travel.Legs[0].BookingRef = ""
+				travel.Legs[0].Distance = nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sharedTravel()
+			tt.link.Redact(&got)
+
+			want := sharedTravel()
+			tt.apply(&want)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Redact() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Track - трек, импортированный из GPX. Сегменты хранятся раздельно, чтобы на карте не соединять разрывы записи
+type Track struct {
+	ID            uuid.UUID      `json:"id"`
+	TravelID      uuid.UUID      `json:"travel_id"`
+	Name          string         `json:"name"`
+	Segments      [][]TrackPoint `json:"segments"`
+	Distance      float64        `json:"distance"`       // метры
+	ElevationGain float64        `json:"elevation_gain"` // метры
+	StartedAt     *time.Time     `json:"started_at,omitempty"`
+	FinishedAt    *time.Time     `json:"finished_at,omitempty"`
+}
+
+type TrackPoint struct {
+	Lat  float64    `json:"lat"`
+	Lon  float64    `json:"lon"`
+	Ele  *float64   `json:"ele,omitempty"`
+	Time *time.Time `json:"time,omitempty"`
+}
+
+// TrackImport - результат импорта GPX-файла в путешествие
+type TrackImport struct {
+	Places    []Place      `json:"places"`
+	Tracks    []Track      `json:"tracks"`
+	DateStart DateOnlyTime `json:"date_start"`
+	DateEnd   DateOnlyTime `json:"date_end"`
+}
@@ -0,0 +1,17 @@
+package ds
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// TrashItem - путешествие или место в корзине. PurgeAt - когда оно будет удалено окончательно,
+// пусто, если корзина не очищается
+type TrashItem struct {
+	ID        uuid.UUID  `json:"id"`
+	TravelID  uuid.UUID  `json:"travel_id"`
+	Name      string     `json:"name"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	DeletedBy *uuid.UUID `json:"deleted_by,omitempty"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
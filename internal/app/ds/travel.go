@@ -13,6 +13,7 @@ type FullTravel struct {
 	DateStart   DateOnlyTime `json:"date_start"`
 	DateEnd     DateOnlyTime `json:"date_end"`
 	Places      []FullPlace  `json:"places"`
+	Legs        []FullLeg    `json:"legs"`
 	Preview     string       `json:"preview"`
 }
 
@@ -32,6 +33,7 @@ type TravelCard struct {
 	DateStart DateOnlyTime `json:"date_start"`
 	DateEnd   DateOnlyTime `json:"date_end"`
 	Preview   string       `json:"preview"`
+	Role      Role         `json:"role"`
 }
 
 type DateOnlyTime struct {
@@ -0,0 +1,124 @@
+package geo
+
+import (
+	"time"
+
+	"lts/internal/app/ds"
+)
+
+// FeatureCollection - GeoJSON FeatureCollection (RFC 7946)
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Geometry - Point или LineString. Координаты идут в порядке [долгота, широта]
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// Route возвращает места с координатами в порядке посещения:
+// по дате, а при совпадении дат - в порядке добавления в путешествие
+func Route(places []ds.Place) []ds.Place {
+	route := make([]ds.Place, 0, len(places))
+	for _, place := range places {
+		if place.HasLocation() {
+			route = append(route, place)
+		}
+	}
+
+	ds.SortByVisit(route)
+
+	return route
+}
+
+// TravelFeatureCollection строит коллекцию из точек мест, линии маршрута между ними
+// и импортированных треков (MultiLineString по сегментам)
+func TravelFeatureCollection(travel ds.Travel, places []ds.Place, tracks []ds.Track) FeatureCollection {
+	route := Route(places)
+
+	collection := FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]Feature, 0, len(route)+len(tracks)+1),
+	}
+
+	line := make([][]float64, 0, len(route))
+
+	for i, place := range route {
+		point := []float64{*place.Longitude, *place.Latitude}
+		line = append(line, point)
+
+		properties := map[string]any{
+			"id":    place.ID,
+			"name":  place.Name,
+			"order": i,
+		}
+		if !place.Date.IsZero() {
+			properties["date"] = place.Date.Format(time.DateOnly)
+		}
+		if place.Address != "" {
+			properties["address"] = place.Address
+		}
+		if place.CountryCode != "" {
+			properties["country_code"] = place.CountryCode
+		}
+
+		collection.Features = append(collection.Features, Feature{
+			Type:       "Feature",
+			Geometry:   Geometry{Type: "Point", Coordinates: point},
+			Properties: properties,
+		})
+	}
+
+	// LineString по спецификации требует минимум две точки
+	if len(line) >= 2 {
+		collection.Features = append(collection.Features, Feature{
+			Type:     "Feature",
+			Geometry: Geometry{Type: "LineString", Coordinates: line},
+			Properties: map[string]any{
+				"travel_id": travel.ID,
+				"name":      travel.Name,
+				"kind":      "route",
+			},
+		})
+	}
+
+	for _, track := range tracks {
+		lines := make([][][]float64, 0, len(track.Segments))
+		for _, segment := range track.Segments {
+			if len(segment) < 2 {
+				continue
+			}
+			line := make([][]float64, 0, len(segment))
+			for _, point := range segment {
+				line = append(line, []float64{point.Lon, point.Lat})
+			}
+			lines = append(lines, line)
+		}
+
+		if len(lines) == 0 {
+			continue
+		}
+
+		collection.Features = append(collection.Features, Feature{
+			Type:     "Feature",
+			Geometry: Geometry{Type: "MultiLineString", Coordinates: lines},
+			Properties: map[string]any{
+				"id":             track.ID,
+				"name":           track.Name,
+				"kind":           "track",
+				"distance":       track.Distance,
+				"elevation_gain": track.ElevationGain,
+			},
+		})
+	}
+
+	return collection
+}
@@ -0,0 +1,44 @@
+package geo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"lts/internal/app/ds"
+)
+
+func TestRoute(t *testing.T) {
+	coord := func(v float64) *float64 { return &v }
+	place := func(name string, day int, located bool) ds.Place {
+		p := ds.Place{Name: name, Date: ds.DateOnlyTime{Time: time.Date(2024, 7, day, 0, 0, 0, 0, time.UTC)}}
+		if located {
+			p.Latitude, p.Longitude = coord(float64(day)), coord(float64(day))
+		}
+		return p
+	}
+
+	tests := []struct {
+		name   string
+		places []ds.Place
+		want   []string
+	}{
+		{"empty", nil, []string{}},
+		{"without location", []ds.Place{place("a", 1, false)}, []string{}},
+		{"by date", []ds.Place{place("c", 3, true), place("a", 1, true), place("b", 2, true)}, []string{"a", "b", "c"}},
+		{"same date keeps order", []ds.Place{place("b", 2, true), place("a", 1, true), place("b2", 2, true)}, []string{"a", "b", "b2"}},
+		{"skips places without location", []ds.Place{place("a", 1, true), place("x", 2, false), place("c", 3, true)}, []string{"a", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{}
+			for _, p := range Route(tt.places) {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Fatalf("Route() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package geo
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"lts/internal/app/ds"
+)
+
+const (
+	gpxNamespace      = "http://www.topografix.com/GPX/1/1"
+	gpxSchemaLocation = "http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd"
+	xsiNamespace      = "http://www.w3.org/2001/XMLSchema-instance"
+	creator           = "LTS"
+)
+
+// GPX - документ GPX 1.1. Порядок полей повторяет порядок элементов в схеме gpx.xsd
+type GPX struct {
+	XMLName        xml.Name   `xml:"gpx"`
+	Xmlns          string     `xml:"xmlns,attr,omitempty"`
+	XmlnsXsi       string     `xml:"xmlns:xsi,attr,omitempty"`
+	SchemaLocation string     `xml:"xsi:schemaLocation,attr,omitempty"`
+	Version        string     `xml:"version,attr"`
+	Creator        string     `xml:"creator,attr"`
+	Metadata       *Metadata  `xml:"metadata,omitempty"`
+	Waypoints      []Waypoint `xml:"wpt"`
+	Tracks         []Track    `xml:"trk"`
+}
+
+type Metadata struct {
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+// Waypoint - wptType: используется и для wpt, и для trkpt
+type Waypoint struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele,omitempty"`
+	Time string   `xml:"time,omitempty"`
+	Name string   `xml:"name,omitempty"`
+	Desc string   `xml:"desc,omitempty"`
+}
+
+type Track struct {
+	Name     string         `xml:"name,omitempty"`
+	Desc     string         `xml:"desc,omitempty"`
+	Segments []TrackSegment `xml:"trkseg"`
+}
+
+type TrackSegment struct {
+	Points []Waypoint `xml:"trkpt"`
+}
+
+// TravelGPX строит GPX: места становятся путевыми точками, маршрут между ними - треком
+func TravelGPX(travel ds.Travel, places []ds.Place) GPX {
+	route := Route(places)
+
+	doc := GPX{
+		Xmlns:          gpxNamespace,
+		XmlnsXsi:       xsiNamespace,
+		SchemaLocation: gpxSchemaLocation,
+		Version:        "1.1",
+		Creator:        creator,
+		Metadata: &Metadata{
+			Name: travel.Name,
+			Desc: travel.Description,
+			Time: formatDateTime(travel.DateStart),
+		},
+		Waypoints: make([]Waypoint, 0, len(route)),
+	}
+
+	segment := TrackSegment{Points: make([]Waypoint, 0, len(route))}
+
+	for _, place := range route {
+		doc.Waypoints = append(doc.Waypoints, Waypoint{
+			Lat:  *place.Latitude,
+			Lon:  *place.Longitude,
+			Time: formatDateTime(place.Date),
+			Name: place.Name,
+			Desc: place.Story,
+		})
+
+		segment.Points = append(segment.Points, Waypoint{
+			Lat:  *place.Latitude,
+			Lon:  *place.Longitude,
+			Time: formatDateTime(place.Date),
+			Name: place.Name,
+		})
+	}
+
+	if len(segment.Points) > 0 {
+		doc.Tracks = []Track{{Name: travel.Name, Segments: []TrackSegment{segment}}}
+	}
+
+	return doc
+}
+
+// WriteGPX записывает путешествие в формате GPX 1.1
+func WriteGPX(w io.Writer, travel ds.Travel, places []ds.Place) error {
+	return writeXML(w, TravelGPX(travel, places))
+}
+
+func writeXML(w io.Writer, v any) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	err = enc.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// formatDateTime форматирует дату как xsd:dateTime, для пустой даты возвращает пустую строку
+func formatDateTime(date ds.DateOnlyTime) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.UTC().Format(time.RFC3339)
+}
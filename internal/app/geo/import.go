@@ -0,0 +1,146 @@
+package geo
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"lts/internal/app/ds"
+)
+
+const earthRadius = 6371008.8 // средний радиус Земли в метрах
+
+var ErrEmptyGPX = errors.New("gpx contains neither tracks nor waypoints")
+
+// ParseGPX разбирает GPX 1.0/1.1 и возвращает треки и путевые точки в виде сущностей LTS.
+// У путевых точек без времени дата остаётся пустой
+func ParseGPX(r io.Reader) ([]ds.Track, []ds.Place, error) {
+	var doc GPX
+
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[xml.Decode]: %w", err)
+	}
+
+	tracks := make([]ds.Track, 0, len(doc.Tracks))
+	for _, trk := range doc.Tracks {
+		track := ds.Track{Name: trk.Name}
+
+		for _, seg := range trk.Segments {
+			segment := make([]ds.TrackPoint, 0, len(seg.Points))
+			for _, pt := range seg.Points {
+				point, err := trackPoint(pt)
+				if err != nil {
+					return nil, nil, err
+				}
+				segment = append(segment, point)
+			}
+			if len(segment) > 0 {
+				track.Segments = append(track.Segments, segment)
+			}
+		}
+
+		if len(track.Segments) == 0 {
+			continue
+		}
+
+		TrackStats(&track)
+		tracks = append(tracks, track)
+	}
+
+	places := make([]ds.Place, 0, len(doc.Waypoints))
+	for _, wpt := range doc.Waypoints {
+		point, err := trackPoint(wpt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		place := ds.Place{
+			Name:      wpt.Name,
+			Story:     wpt.Desc,
+			Latitude:  &point.Lat,
+			Longitude: &point.Lon,
+		}
+		if point.Time != nil {
+			place.Date = ds.DateOnlyTime{Time: truncateDate(*point.Time)}
+		}
+
+		places = append(places, place)
+	}
+
+	if len(tracks) == 0 && len(places) == 0 {
+		return nil, nil, ErrEmptyGPX
+	}
+
+	return tracks, places, nil
+}
+
+func trackPoint(wpt Waypoint) (ds.TrackPoint, error) {
+	if wpt.Lat < -90 || wpt.Lat > 90 || wpt.Lon < -180 || wpt.Lon > 180 {
+		return ds.TrackPoint{}, fmt.Errorf("point %v,%v is out of range", wpt.Lat, wpt.Lon)
+	}
+
+	point := ds.TrackPoint{Lat: wpt.Lat, Lon: wpt.Lon, Ele: wpt.Ele}
+
+	if wpt.Time != "" {
+		t, err := time.Parse(time.RFC3339, wpt.Time)
+		if err != nil {
+			return ds.TrackPoint{}, fmt.Errorf("invalid point time %q: %w", wpt.Time, err)
+		}
+		point.Time = &t
+	}
+
+	return point, nil
+}
+
+// TrackStats заполняет дистанцию, набор высоты и время начала/окончания трека.
+// Расстояние и набор высоты не считаются через разрывы между сегментами
+func TrackStats(track *ds.Track) {
+	track.Distance = 0
+	track.ElevationGain = 0
+	track.StartedAt = nil
+	track.FinishedAt = nil
+
+	for _, segment := range track.Segments {
+		for i, point := range segment {
+			if point.Time != nil {
+				if track.StartedAt == nil || point.Time.Before(*track.StartedAt) {
+					track.StartedAt = point.Time
+				}
+				if track.FinishedAt == nil || point.Time.After(*track.FinishedAt) {
+					track.FinishedAt = point.Time
+				}
+			}
+
+			if i == 0 {
+				continue
+			}
+
+			prev := segment[i-1]
+			track.Distance += Distance(prev.Lat, prev.Lon, point.Lat, point.Lon)
+
+			if prev.Ele != nil && point.Ele != nil && *point.Ele > *prev.Ele {
+				track.ElevationGain += *point.Ele - *prev.Ele
+			}
+		}
+	}
+}
+
+// Distance - расстояние по большому кругу между двумя точками в метрах (формула гаверсинусов)
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180
+	lat2Rad := lat2 * math.Pi / 180
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,137 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 55.75, 37.62, 55.75, 37.62, 0},
+		{"one degree of latitude", 0, 0, 1, 0, earthRadius * math.Pi / 180},
+		{"one degree of longitude on equator", 0, 0, 0, 1, earthRadius * math.Pi / 180},
+		{"equator to pole", 0, 0, 90, 0, earthRadius * math.Pi / 2},
+		{"antipodes", 0, 0, 0, 180, earthRadius * math.Pi},
+		{"across antimeridian", 0, 179.5, 0, -179.5, earthRadius * math.Pi / 180},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Fatalf("Distance() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGPX(t *testing.T) {
+	const header = `<?xml version="1.0"?><gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">`
+
+	tests := []struct {
+		name      string
+		gpx       string
+		tracks    int
+		places    int
+		errString string
+		err       error
+	}{
+		{"empty", header + `</gpx>`, 0, 0, "", ErrEmptyGPX},
+		{"empty track", header + `<trk><name>t</name><trkseg></trkseg></trk></gpx>`, 0, 0, "", ErrEmptyGPX},
+		{"not xml", `lat,lon`, 0, 0, "[xml.Decode]", nil},
+		{"latitude out of range", header + `<wpt lat="91" lon="0"/></gpx>`, 0, 0, "out of range", nil},
+		{"longitude out of range", header + `<trk><trkseg><trkpt lat="0" lon="-181"/></trkseg></trk></gpx>`, 0, 0, "out of range", nil},
+		{"invalid time", header + `<wpt lat="1" lon="2"><time>yesterday</time></wpt></gpx>`, 0, 0, "invalid point time", nil},
+		{"waypoints", header + `<wpt lat="1" lon="2"><name>a</name></wpt><wpt lat="3" lon="4"/></gpx>`, 0, 2, "", nil},
+		{"track and waypoint", header + `<wpt lat="1" lon="2"/><trk><trkseg><trkpt lat="0" lon="0"/><trkpt lat="0" lon="1"/></trkseg><trkseg/></trk></gpx>`, 1, 1, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks, places, err := ParseGPX(strings.NewReader(tt.gpx))
+			switch {
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("ParseGPX() error = %v, want %v", err, tt.err)
+				}
+				return
+			case tt.errString != "":
+				if err == nil || !strings.Contains(err.Error(), tt.errString) {
+					t.Fatalf("ParseGPX() error = %v, want containing %q", err, tt.errString)
+				}
+				return
+			case err != nil:
+				t.Fatalf("ParseGPX() error = %v", err)
+			}
+
+			if len(tracks) != tt.tracks || len(places) != tt.places {
+				t.Fatalf("ParseGPX() = %d tracks, %d places, want %d, %d", len(tracks), len(places), tt.tracks, tt.places)
+			}
+		})
+	}
+}
+
+func TestParseGPXWaypoint(t *testing.T) {
+	gpx := `<gpx version="1.1" creator="test">
+		<wpt lat="55.75" lon="37.62"><time>2024-07-15T23:30:00+03:00</time><name>Moscow</name><desc>Red square</desc></wpt>
+		<wpt lat="59.94" lon="30.31"/>
+	</gpx>`
+
+	_, places, err := ParseGPX(strings.NewReader(gpx))
+	if err != nil {
+		t.Fatalf("ParseGPX() error = %v", err)
+	}
+
+	moscow := places[0]
+	if moscow.Name != "Moscow" || moscow.Story != "Red square" || *moscow.Latitude != 55.75 || *moscow.Longitude != 37.62 {
+		t.Fatalf("unexpected place %+v", moscow)
+	}
+	// Дата берётся из времени точки как есть, без перевода в UTC
+	if want := time.Date(2024, 7, 15, 0, 0, 0, 0, time.UTC); !moscow.Date.Equal(want) {
+		t.Fatalf("place date = %v, want %v", moscow.Date, want)
+	}
+
+	if !places[1].Date.IsZero() {
+		t.Fatalf("untimed waypoint must have zero date, got %v", places[1].Date)
+	}
+}
+
+func TestTrackStats(t *testing.T) {
+	gpx := `<gpx version="1.1" creator="test"><trk><name>walk</name>
+		<trkseg>
+			<trkpt lat="0" lon="0"><ele>100</ele><time>2024-07-16T10:00:00Z</time></trkpt>
+			<trkpt lat="0" lon="1"><ele>150</ele><time>2024-07-16T11:00:00Z</time></trkpt>
+			<trkpt lat="0" lon="2"><ele>120</ele></trkpt>
+		</trkseg>
+		<trkseg>
+			<trkpt lat="10" lon="10"><ele>100</ele><time>2024-07-15T09:00:00Z</time></trkpt>
+			<trkpt lat="11" lon="10"><ele>130</ele></trkpt>
+		</trkseg>
+	</trk></gpx>`
+
+	tracks, _, err := ParseGPX(strings.NewReader(gpx))
+	if err != nil {
+		t.Fatalf("ParseGPX() error = %v", err)
+	}
+
+	track := tracks[0]
+	degree := earthRadius * math.Pi / 180
+
+	// Разрыв между сегментами в дистанцию не входит
+	if math.Abs(track.Distance-3*degree) > 0.01 {
+		t.Fatalf("Distance = %f, want %f", track.Distance, 3*degree)
+	}
+	if track.ElevationGain != 80 {
+		t.Fatalf("ElevationGain = %f, want 80", track.ElevationGain)
+	}
+	if !track.StartedAt.Equal(time.Date(2024, 7, 15, 9, 0, 0, 0, time.UTC)) || !track.FinishedAt.Equal(time.Date(2024, 7, 16, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("track time = %v - %v", track.StartedAt, track.FinishedAt)
+	}
+}
@@ -0,0 +1,100 @@
+package geo
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"lts/internal/app/ds"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+// KML - документ KML 2.2. Порядок полей повторяет порядок элементов в схеме ogckml22.xsd
+type KML struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document KMLDocument `xml:"Document"`
+}
+
+type KMLDocument struct {
+	Name        string         `xml:"name,omitempty"`
+	Description string         `xml:"description,omitempty"`
+	Placemarks  []KMLPlacemark `xml:"Placemark"`
+}
+
+type KMLPlacemark struct {
+	Name        string         `xml:"name,omitempty"`
+	Address     string         `xml:"address,omitempty"`
+	Description string         `xml:"description,omitempty"`
+	TimeStamp   *KMLTimeStamp  `xml:"TimeStamp,omitempty"`
+	Point       *KMLPoint      `xml:"Point,omitempty"`
+	LineString  *KMLLineString `xml:"LineString,omitempty"`
+}
+
+type KMLTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type KMLPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type KMLLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// TravelKML строит KML: места становятся метками, маршрут между ними - линией
+func TravelKML(travel ds.Travel, places []ds.Place) KML {
+	route := Route(places)
+
+	doc := KML{
+		Xmlns: kmlNamespace,
+		Document: KMLDocument{
+			Name:        travel.Name,
+			Description: travel.Description,
+			Placemarks:  make([]KMLPlacemark, 0, len(route)+1),
+		},
+	}
+
+	line := make([]string, 0, len(route))
+
+	for _, place := range route {
+		coordinates := kmlCoordinates(place)
+		line = append(line, coordinates)
+
+		placemark := KMLPlacemark{
+			Name:        place.Name,
+			Address:     place.Address,
+			Description: place.Story,
+			Point:       &KMLPoint{Coordinates: coordinates},
+		}
+		if !place.Date.IsZero() {
+			placemark.TimeStamp = &KMLTimeStamp{When: place.Date.Format(time.DateOnly)}
+		}
+
+		doc.Document.Placemarks = append(doc.Document.Placemarks, placemark)
+	}
+
+	if len(line) >= 2 {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, KMLPlacemark{
+			Name:       travel.Name,
+			LineString: &KMLLineString{Tessellate: 1, Coordinates: strings.Join(line, " ")},
+		})
+	}
+
+	return doc
+}
+
+// WriteKML записывает путешествие в формате KML 2.2
+func WriteKML(w io.Writer, travel ds.Travel, places []ds.Place) error {
+	return writeXML(w, TravelKML(travel, places))
+}
+
+// kmlCoordinates - кортеж "долгота,широта" в нотации KML
+func kmlCoordinates(place ds.Place) string {
+	return strconv.FormatFloat(*place.Longitude, 'f', -1, 64) + "," + strconv.FormatFloat(*place.Latitude, 'f', -1, 64)
+}
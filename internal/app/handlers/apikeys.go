@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// keyPrefixLength - сколько первых символов ключа хранится открыто, чтобы узнать его в списке
+const keyPrefixLength = 12
+
+var errKeyByKey = errors.New("api keys can be managed only after logging in with a password")
+
+type APIKeyHandlerImplemented struct {
+	APIKeyHandler
+}
+
+type APIKeyHandlerImpl struct {
+	APIKeyRepo repository.APIKeyRepository
+}
+
+func NewAPIKeyHandlerImpl(apiKeyRepo repository.APIKeyRepository) *APIKeyHandlerImpl {
+	return &APIKeyHandlerImpl{
+		APIKeyRepo: apiKeyRepo,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary      Create an API key
+// @Description  Create a named API key with scopes (read, write, media). The key is returned only once
+// @Tags         Auth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        key body ds.APIKeyRequest true "Name, scopes and optional expiry"
+// @Success      201 {object} ds.APIKey "Successfully created key"
+// @Failure      400 "Invalid name, scopes or expiry"
+// @Failure      403 "Keys cannot be created with an API key"
+// @Failure      500 "Internal server error"
+// @Router       /auth/keys [post]
+func (kh APIKeyHandlerImpl) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !kh.bySession(w, r) {
+		return
+	}
+
+	var request ds.APIKeyRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	scopes, err := uniqueScopes(request.Scopes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	key, hash, err := auth.NewAPIKey()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	apiKey, err := kh.APIKeyRepo.CreateAPIKey(r.Context(), ds.APIKey{
+		Name:      request.Name,
+		Prefix:    key[:keyPrefixLength],
+		Scopes:    scopes,
+		ExpiresAt: request.ExpiresAt,
+	}, hash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	apiKey.Key = key
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(apiKey)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// GetAPIKeys godoc
+// @Summary      Get API keys
+// @Description  Retrieve API keys of the current user that are not revoked
+// @Tags         Auth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200 {array} ds.APIKey "Successfully retrieved keys"
+// @Failure      403 "Keys cannot be listed with an API key"
+// @Failure      500 "Internal server error"
+// @Router       /auth/keys [get]
+func (kh APIKeyHandlerImpl) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !kh.bySession(w, r) {
+		return
+	}
+
+	keys, err := kh.APIKeyRepo.GetAPIKeys(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(keys)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke an API key
+// @Description  Revoke an API key of the current user. The key stops working immediately
+// @Tags         Auth
+// @Security     BearerAuth
+// @Param        uuid path string true "UUID of the key"
+// @Success      200 "Successfully revoked key"
+// @Failure      400 "Invalid UUID format"
+// @Failure      403 "Keys cannot be revoked with an API key"
+// @Failure      404 "Key not found"
+// @Failure      500 "Internal server error"
+// @Router       /auth/keys/{uuid} [delete]
+func (kh APIKeyHandlerImpl) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !kh.bySession(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	UUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = kh.APIKeyRepo.RevokeAPIKey(r.Context(), UUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// bySession не даёт управлять ключами по API-ключу: иначе ключ мог бы выпустить себе более широкие права
+func (kh APIKeyHandlerImpl) bySession(w http.ResponseWriter, r *http.Request) bool {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, repository.ErrUnauthenticated)
+		return false
+	}
+	if principal.APIKeyID != uuid.Nil {
+		http.Error(w, errKeyByKey.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func uniqueScopes(scopes []ds.Scope) ([]ds.Scope, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	result := make([]ds.Scope, 0, len(scopes))
+	seen := make(map[ds.Scope]bool, len(scopes))
+
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, errors.New("unknown scope " + string(scope))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
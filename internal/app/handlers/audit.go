@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+type AuditHandlerImplemented struct {
+	AuditHandler
+}
+
+type AuditHandlerImpl struct {
+	AuditRepo repository.AuditRepository
+}
+
+func NewAuditHandlerImpl(auditRepo repository.AuditRepository) *AuditHandlerImpl {
+	return &AuditHandlerImpl{
+		AuditRepo: auditRepo,
+	}
+}
+
+// GetHistory godoc
+// @Summary      Get travel history
+// @Description  Retrieve the audit log of the travel: who created, changed or deleted travel, places, expenses, legs and media. Newest entries first; pass the id of the last entry as before to get the next page
+// @Tags         Audit
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        before query int false "Return entries older than this id"
+// @Param        limit query int false "Page size, 50 by default, at most 200"
+// @Success      200 {array} ds.AuditEntry "Successfully retrieved history"
+// @Failure      400 "Invalid UUID format or paging parameters"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/history [get]
+func (ah AuditHandlerImpl) GetHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var before int64
+	if s := r.URL.Query().Get("before"); s != "" {
+		before, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || before < 1 {
+			http.Error(w, "before must be a positive entry id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultHistoryLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxHistoryLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := ah.AuditRepo.GetHistory(r.Context(), travelUUID, before, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(entries)
+	if err != nil {
+		writeError(w, err)
+	}
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuthHandlerImplemented struct {
+	AuthHandler
+}
+
+type AuthHandlerImpl struct {
+	UserRepo   repository.UserRepository
+	TokenRepo  repository.TokenRepository
+	Tokens     *auth.TokenManager
+	RefreshTTL time.Duration
+}
+
+func NewAuthHandlerImpl(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, tokens *auth.TokenManager, refreshTTL time.Duration) *AuthHandlerImpl {
+	return &AuthHandlerImpl{
+		UserRepo:   userRepo,
+		TokenRepo:  tokenRepo,
+		Tokens:     tokens,
+		RefreshTTL: refreshTTL,
+	}
+}
+
+// Register godoc
+// @Summary      Register a new user
+// @Description  Create a user account and issue an access/refresh token pair
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        credentials body ds.Credentials true "Email, password and name"
+// @Success      201 {object} ds.TokenPair "Successfully registered"
+// @Failure      400 "Invalid email, weak or too long password"
+// @Failure      409 "User with this email already exists"
+// @Failure      500 "Internal server error"
+// @Router       /auth/register [post]
+func (ah AuthHandlerImpl) Register(w http.ResponseWriter, r *http.Request) {
+	var credentials ds.Credentials
+
+	err := json.NewDecoder(r.Body).Decode(&credentials)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = mail.ParseAddress(credentials.Email)
+	if err != nil {
+		http.Error(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := auth.HashPassword(credentials.Password)
+	if errors.Is(err, auth.ErrWeakPassword) || errors.Is(err, auth.ErrLongPassword) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := ah.UserRepo.CreateUser(r.Context(), ds.User{Email: credentials.Email, Name: credentials.Name, PasswordHash: hash})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ah.writeTokens(w, r, user, http.StatusCreated)
+}
+
+// Login godoc
+// @Summary      Log in
+// @Description  Check email and password and issue a new access/refresh token pair
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        credentials body ds.Credentials true "Email and password"
+// @Success      200 {object} ds.TokenPair "Successfully logged in"
+// @Failure      400 "Invalid request body"
+// @Failure      401 "Wrong email or password"
+// @Failure      500 "Internal server error"
+// @Router       /auth/login [post]
+func (ah AuthHandlerImpl) Login(w http.ResponseWriter, r *http.Request) {
+	var credentials ds.Credentials
+
+	err := json.NewDecoder(r.Body).Decode(&credentials)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := ah.UserRepo.GetUserByEmail(r.Context(), credentials.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		writeError(w, err)
+		return
+	}
+
+	// Одинаковый ответ и время ответа для неизвестного email и неверного пароля
+	var valid bool
+	if err != nil {
+		valid = auth.CheckNoPassword(credentials.Password)
+	} else {
+		valid = auth.CheckPassword(user.PasswordHash, credentials.Password)
+	}
+	if !valid {
+		http.Error(w, "wrong email or password", http.StatusUnauthorized)
+		return
+	}
+
+	ah.writeTokens(w, r, user, http.StatusOK)
+}
+
+// Refresh godoc
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for a new token pair. The presented refresh token is revoked;
+// @Description  presenting an already rotated token revokes the whole chain
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        token body ds.RefreshRequest true "Refresh token"
+// @Success      200 {object} ds.TokenPair "New token pair"
+// @Failure      400 "Invalid request body"
+// @Failure      401 "Invalid, expired or reused refresh token"
+// @Failure      500 "Internal server error"
+// @Router       /auth/refresh [post]
+func (ah AuthHandlerImpl) Refresh(w http.ResponseWriter, r *http.Request) {
+	var request ds.RefreshRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.RefreshToken == "" {
+		http.Error(w, "refresh_token is required", http.StatusBadRequest)
+		return
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	next := ds.RefreshToken{
+		ID:        uuid.New(),
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ah.RefreshTTL),
+	}
+
+	user, err := ah.TokenRepo.RotateRefreshToken(r.Context(), auth.HashToken(request.RefreshToken), next)
+	if errors.Is(err, repository.ErrTokenReused) {
+		logging.FromContext(r.Context()).Warnf("refresh token reuse detected, token family revoked")
+		http.Error(w, "invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ah.writePair(w, user, token, next.ExpiresAt, http.StatusOK)
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  Revoke the refresh token and all tokens rotated from the same login.
+// @Description  Access tokens already issued stay valid until they expire
+// @Tags         Auth
+// @Accept       json
+// @Param        token body ds.RefreshRequest true "Refresh token"
+// @Success      204 "Successfully logged out"
+// @Failure      400 "Invalid request body"
+// @Failure      500 "Internal server error"
+// @Router       /auth/logout [post]
+func (ah AuthHandlerImpl) Logout(w http.ResponseWriter, r *http.Request) {
+	var request ds.RefreshRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.RefreshToken == "" {
+		http.Error(w, "refresh_token is required", http.StatusBadRequest)
+		return
+	}
+
+	err = ah.TokenRepo.RevokeRefreshToken(r.Context(), auth.HashToken(request.RefreshToken))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me godoc
+// @Summary      Get current user
+// @Description  Retrieve the account of the authenticated user
+// @Tags         Auth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200 {object} ds.User "Current user"
+// @Failure      401 "Missing or invalid token"
+// @Failure      500 "Internal server error"
+// @Router       /auth/me [get]
+func (ah AuthHandlerImpl) Me(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, repository.ErrUnauthenticated)
+		return
+	}
+
+	user, err := ah.UserRepo.GetUser(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(user)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// writeTokens открывает новую цепочку refresh-токенов и отдаёт пару токенов
+func (ah AuthHandlerImpl) writeTokens(w http.ResponseWriter, r *http.Request, user ds.User, status int) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	refresh := ds.RefreshToken{
+		ID:        uuid.New(),
+		TokenHash: hash,
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		ExpiresAt: time.Now().Add(ah.RefreshTTL),
+	}
+
+	err = ah.TokenRepo.CreateRefreshToken(r.Context(), refresh)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ah.writePair(w, user, token, refresh.ExpiresAt, status)
+}
+
+func (ah AuthHandlerImpl) writePair(w http.ResponseWriter, user ds.User, refreshToken string, refreshExpiresAt time.Time, status int) {
+	accessToken, accessExpiresAt, err := ah.Tokens.Issue(auth.Principal{UserID: user.ID, Email: user.Email})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(ds.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+		User:             user,
+	})
+	if err != nil {
+		writeError(w, err)
+	}
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/ds"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultCommentLimit = 20
+	maxCommentLimit     = 100
+	maxCommentLength    = 5000
+	maxEmojiLength      = 32
+)
+
+type CommentHandlerImplemented struct {
+	CommentHandler
+}
+
+type CommentHandlerImpl struct {
+	CommentRepo repository.CommentRepository
+}
+
+func NewCommentHandlerImpl(commentRepo repository.CommentRepository) *CommentHandlerImpl {
+	return &CommentHandlerImpl{
+		CommentRepo: commentRepo,
+	}
+}
+
+// GetTravelComments godoc
+// @Summary      Get travel comments
+// @Description  Retrieve a page of top-level comments on the travel with their replies and reactions, oldest first. Pass the id of the last comment as after to get the next page
+// @Tags         Comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        after query string false "Return comments after this one"
+// @Param        limit query int false "Page size, 20 by default, at most 100"
+// @Success      200 {array} ds.Comment "Successfully retrieved comments"
+// @Failure      400 "Invalid UUID format or paging parameters"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/comments [get]
+func (ch CommentHandlerImpl) GetTravelComments(w http.ResponseWriter, r *http.Request) {
+	travelUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ch.getComments(w, r, travelUUID, uuid.Nil)
+}
+
+// CreateTravelComment godoc
+// @Summary      Comment on the travel
+// @Description  Leave a comment on the travel or reply to a top-level comment. Any member of the travel can comment
+// @Tags         Comments
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        comment body ds.CommentRequest true "Comment text and optional parent comment"
+// @Success      201 {object} ds.Comment "Successfully created comment"
+// @Failure      400 "Invalid UUID format or comment text"
+// @Failure      403 "Public link visitors cannot comment"
+// @Failure      404 "Travel or parent comment not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/comments [post]
+func (ch CommentHandlerImpl) CreateTravelComment(w http.ResponseWriter, r *http.Request) {
+	travelUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ch.createComment(w, r, ds.Comment{TravelID: travelUUID})
+}
+
+// GetPlaceComments godoc
+// @Summary      Get place comments
+// @Description  Retrieve a page of top-level comments on the place with their replies and reactions, oldest first. Pass the id of the last comment as after to get the next page
+// @Tags         Comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the place"
+// @Param        after query string false "Return comments after this one"
+// @Param        limit query int false "Page size, 20 by default, at most 100"
+// @Success      200 {array} ds.Comment "Successfully retrieved comments"
+// @Failure      400 "Invalid UUID format or paging parameters"
+// @Failure      404 "Place not found"
+// @Failure      500 "Internal server error"
+// @Router       /place/{uuid}/comments [get]
+func (ch CommentHandlerImpl) GetPlaceComments(w http.ResponseWriter, r *http.Request) {
+	placeUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ch.getComments(w, r, uuid.Nil, placeUUID)
+}
+
+// CreatePlaceComment godoc
+// @Summary      Comment on the place
+// @Description  Leave a comment on the place or reply to a top-level comment. Any member of the travel can comment
+// @Tags         Comments
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        uuid path string true "UUID of the place"
+// @Param        comment body ds.CommentRequest true "Comment text and optional parent comment"
+// @Success      201 {object} ds.Comment "Successfully created comment"
+// @Failure      400 "Invalid UUID format or comment text"
+// @Failure      403 "Public link visitors cannot comment"
+// @Failure      404 "Place or parent comment not found"
+// @Failure      500 "Internal server error"
+// @Router       /place/{uuid}/comments [post]
+func (ch CommentHandlerImpl) CreatePlaceComment(w http.ResponseWriter, r *http.Request) {
+	placeUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ch.createComment(w, r, ds.Comment{PlaceID: &placeUUID})
+}
+
+// UpdateComment godoc
+// @Summary      Edit a comment
+// @Description  Change the text of the comment. Only the author can edit it
+// @Tags         Comments
+// @Security     BearerAuth
+// @Accept       json
+// @Param        uuid path string true "UUID of the comment"
+// @Param        comment body ds.CommentRequest true "New comment text"
+// @Success      200 "Successfully updated comment"
+// @Failure      400 "Invalid UUID format or comment text"
+// @Failure      403 "Only the author can edit the comment"
+// @Failure      404 "Comment not found"
+// @Failure      500 "Internal server error"
+// @Router       /comments/{uuid} [put]
+func (ch CommentHandlerImpl) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	commentUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, ok := commentBody(w, r)
+	if !ok {
+		return
+	}
+
+	err = ch.CommentRepo.UpdateComment(r.Context(), commentUUID, body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteComment godoc
+// @Summary      Delete a comment
+// @Description  Erase the text of the comment. Replies stay in the thread. The author or a travel owner can delete it
+// @Tags         Comments
+// @Security     BearerAuth
+// @Param        uuid path string true "UUID of the comment"
+// @Success      200 "Successfully deleted comment"
+// @Failure      400 "Invalid UUID format"
+// @Failure      403 "Only the author or an owner can delete the comment"
+// @Failure      404 "Comment not found"
+// @Failure      500 "Internal server error"
+// @Router       /comments/{uuid} [delete]
+func (ch CommentHandlerImpl) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	commentUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ch.CommentRepo.DeleteComment(r.Context(), commentUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// AddReaction godoc
+// @Summary      React to a comment
+// @Description  Add an emoji reaction to the comment on behalf of the user. Adding the same reaction twice has no effect
+// @Tags         Comments
+// @Security     BearerAuth
+// @Param        uuid path string true "UUID of the comment"
+// @Param        emoji path string true "Emoji"
+// @Success      200 "Successfully added reaction"
+// @Failure      400 "Invalid UUID format or emoji"
+// @Failure      404 "Comment not found"
+// @Failure      500 "Internal server error"
+// @Router       /comments/{uuid}/reactions/{emoji} [put]
+func (ch CommentHandlerImpl) AddReaction(w http.ResponseWriter, r *http.Request) {
+	commentUUID, emoji, err := reactionVars(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ch.CommentRepo.AddReaction(r.Context(), commentUUID, emoji)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteReaction godoc
+// @Summary      Remove a reaction
+// @Description  Remove the user's emoji reaction from the comment
+// @Tags         Comments
+// @Security     BearerAuth
+// @Param        uuid path string true "UUID of the comment"
+// @Param        emoji path string true "Emoji"
+// @Success      200 "Successfully removed reaction"
+// @Failure      400 "Invalid UUID format or emoji"
+// @Failure      404 "Comment or reaction not found"
+// @Failure      500 "Internal server error"
+// @Router       /comments/{uuid}/reactions/{emoji} [delete]
+func (ch CommentHandlerImpl) DeleteReaction(w http.ResponseWriter, r *http.Request) {
+	commentUUID, emoji, err := reactionVars(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ch.CommentRepo.DeleteReaction(r.Context(), commentUUID, emoji)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (ch CommentHandlerImpl) getComments(w http.ResponseWriter, r *http.Request, travelUUID, placeUUID uuid.UUID) {
+	var after uuid.UUID
+	var err error
+
+	if s := r.URL.Query().Get("after"); s != "" {
+		after, err = uuid.Parse(s)
+		if err != nil {
+			http.Error(w, "after: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultCommentLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxCommentLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxCommentLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	comments, err := ch.CommentRepo.GetComments(r.Context(), travelUUID, placeUUID, after, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(comments)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (ch CommentHandlerImpl) createComment(w http.ResponseWriter, r *http.Request, comment ds.Comment) {
+	var request ds.CommentRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment.Body, err = validateComment(request.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	comment.ParentID = request.ParentID
+
+	comment, err = ch.CommentRepo.CreateComment(r.Context(), comment)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(comment)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorw("[json.Encode]", "error", err)
+	}
+}
+
+// commentBody читает и проверяет текст комментария из тела запроса, отвечая 400 при ошибке
+func commentBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request ds.CommentRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+
+	body, err := validateComment(request.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return body, true
+}
+
+func validateComment(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("comment must not be empty")
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", errors.New("comment must be at most " + strconv.Itoa(maxCommentLength) + " characters long")
+	}
+	return body, nil
+}
+
+func reactionVars(r *http.Request) (uuid.UUID, string, error) {
+	vars := mux.Vars(r)
+
+	commentUUID, err := uuid.Parse(vars["uuid"])
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	emoji := vars["emoji"]
+	if !validEmoji(emoji) {
+		return uuid.Nil, "", errors.New("reaction must be a single emoji")
+	}
+	return commentUUID, emoji, nil
+}
+
+// validEmoji пропускает короткие последовательности из символов-пиктограмм с модификаторами
+// (ZWJ, селекторы вариантов, оттенки кожи, флаги) и не пропускает обычный текст
+func validEmoji(s string) bool {
+	if s == "" || len(s) > maxEmojiLength || !utf8.ValidString(s) {
+		return false
+	}
+
+	pictographic := false
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r), unicode.Is(unicode.Regional_Indicator, r):
+			pictographic = true
+		case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.Is(unicode.Sk, r),
+			r == '‍', unicode.Is(unicode.Variation_Selector, r), unicode.IsDigit(r), r == '#', r == '*':
+		default:
+			return false
+		}
+	}
+	return pictographic
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"lts/internal/app/ds"
+	"lts/internal/app/repository"
+	"net/http"
+)
+
+// requireRole возвращает repository.ErrForbidden, если у пользователя роль ниже required.
+// Нужна там, где до изменения в БД есть побочные эффекты (файлы, создание расхода)
+func requireRole(ctx context.Context, lookup func(context.Context, uuid.UUID) (ds.Role, error), id uuid.UUID, required ds.Role) error {
+	role, err := lookup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !role.Allows(required) {
+		return repository.ErrForbidden
+	}
+	return nil
+}
+
+// writeError отвечает клиенту статусом, соответствующим ошибке репозитория
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case tooLarge(err):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrUnauthenticated):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, repository.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"go.uber.org/zap"
 	"lts/internal/app/ds"
+	"lts/internal/app/logging"
 	"lts/internal/app/repository"
+	"lts/internal/app/settlement"
 	"net/http"
 )
 
@@ -15,13 +18,15 @@ type ExpensesHandlerImplemented struct {
 }
 
 type ExpensesHandlerImpl struct {
-	ExpensesRepo repository.ExpensesRepository
-	PlaceRepo    repository.PlaceRepository
-	Logger       *zap.SugaredLogger
+	ExpensesRepo    repository.ExpensesRepository
+	PlaceRepo       repository.PlaceRepository
+	LegRepo         repository.LegRepository
+	MemberRepo      repository.MemberRepository
+	ParticipantRepo repository.ParticipantRepository
 }
 
-func NewExpensesHandlerImpl(expensesRepo repository.ExpensesRepository, placeRepo repository.PlaceRepository, logger *zap.SugaredLogger) *ExpensesHandlerImpl {
-	return &ExpensesHandlerImpl{ExpensesRepo: expensesRepo, PlaceRepo: placeRepo, Logger: logger}
+func NewExpensesHandlerImpl(expensesRepo repository.ExpensesRepository, placeRepo repository.PlaceRepository, legRepo repository.LegRepository, memberRepo repository.MemberRepository, participantRepo repository.ParticipantRepository) *ExpensesHandlerImpl {
+	return &ExpensesHandlerImpl{ExpensesRepo: expensesRepo, PlaceRepo: placeRepo, LegRepo: legRepo, MemberRepo: memberRepo, ParticipantRepo: participantRepo}
 }
 
 // CreateExpense godoc
@@ -42,7 +47,7 @@ func (eh ExpensesHandlerImpl) CreateExpense(w http.ResponseWriter, r *http.Reque
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["place_uuid"]
 	if !ok {
-		eh.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	uuidParsed, err := uuid.Parse(uuidStr)
@@ -58,15 +63,124 @@ func (eh ExpensesHandlerImpl) CreateExpense(w http.ResponseWriter, r *http.Reque
 
 	}
 
+	err = settlement.Validate(expense)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = requireRole(r.Context(), eh.MemberRepo.GetPlaceRole, uuidParsed, ds.RoleEditor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	travelID, err := eh.PlaceRepo.GetPlaceTravel(r.Context(), uuidParsed)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = eh.checkMembers(r.Context(), travelID, expense)
+	if errors.Is(err, settlement.ErrUnknownMember) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
 	expense, err = eh.ExpensesRepo.CreateExpense(r.Context(), expense)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	err = eh.PlaceRepo.SetExpenses(r.Context(), expense.ID, uuidParsed)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(expense)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// CreateLegExpense godoc
+// @Summary      Create a new leg expense
+// @Description  Create a new expense entry for a specific transport leg
+// @Tags         Expenses
+// @Accept       json
+// @Produce      json
+// @Param        leg_uuid path string true "UUID of the leg"
+// @Param        expense body ds.Expense true "Expense details"
+// @Success      201 {object} ds.Expense "Successfully created expense"
+// @Failure      400 "Invalid leg UUID or expense data"
+// @Failure      500 "Internal server error"
+// @Router       /expenses/leg/{leg_uuid} [post]
+func (eh ExpensesHandlerImpl) CreateLegExpense(w http.ResponseWriter, r *http.Request) {
+	var expense ds.Expense
+
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["leg_uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("leg uuid is missing in parameters")
+	}
+
+	uuidParsed, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&expense)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = settlement.Validate(expense)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	leg, err := eh.LegRepo.GetLeg(r.Context(), uuidParsed)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = requireRole(r.Context(), eh.MemberRepo.GetRole, leg.TravelID, ds.RoleEditor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = eh.checkMembers(r.Context(), leg.TravelID, expense)
+	if errors.Is(err, settlement.ErrUnknownMember) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	expense, err = eh.ExpensesRepo.CreateExpense(r.Context(), expense)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = eh.LegRepo.SetExpenses(r.Context(), expense.ID, uuidParsed)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -74,7 +188,7 @@ func (eh ExpensesHandlerImpl) CreateExpense(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusCreated)
 	err = json.NewEncoder(w).Encode(expense)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 	}
 }
 
@@ -92,7 +206,7 @@ func (eh ExpensesHandlerImpl) GetExpense(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		eh.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	uuidParsed, err := uuid.Parse(uuidStr)
@@ -103,7 +217,7 @@ func (eh ExpensesHandlerImpl) GetExpense(w http.ResponseWriter, r *http.Request)
 
 	expense, err := eh.ExpensesRepo.GetExpense(r.Context(), uuidParsed)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -111,7 +225,7 @@ func (eh ExpensesHandlerImpl) GetExpense(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(expense)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 	}
 }
 
@@ -133,7 +247,7 @@ func (eh ExpensesHandlerImpl) UpdateExpense(w http.ResponseWriter, r *http.Reque
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		eh.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	uuidParsed, err := uuid.Parse(uuidStr)
@@ -149,9 +263,31 @@ func (eh ExpensesHandlerImpl) UpdateExpense(w http.ResponseWriter, r *http.Reque
 
 	}
 
+	err = settlement.Validate(expense)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	travelID, err := eh.ExpensesRepo.GetExpenseTravel(r.Context(), uuidParsed)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = eh.checkMembers(r.Context(), travelID, expense)
+	if errors.Is(err, settlement.ErrUnknownMember) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
 	expense, err = eh.ExpensesRepo.UpdateExpense(r.Context(), expense, uuidParsed)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -159,7 +295,7 @@ func (eh ExpensesHandlerImpl) UpdateExpense(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(expense)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 	}
 }
 
@@ -177,7 +313,7 @@ func (eh ExpensesHandlerImpl) DeleteExpense(w http.ResponseWriter, r *http.Reque
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		eh.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	uuidParsed, err := uuid.Parse(uuidStr)
@@ -188,10 +324,19 @@ func (eh ExpensesHandlerImpl) DeleteExpense(w http.ResponseWriter, r *http.Reque
 
 	err = eh.ExpensesRepo.DeleteExpense(r.Context(), uuidParsed)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 }
+
+// checkMembers проверяет, что плательщик и доли расхода ссылаются на участников того же путешествия
+func (eh ExpensesHandlerImpl) checkMembers(ctx context.Context, travelID uuid.UUID, expense ds.Expense) error {
+	participants, err := eh.ParticipantRepo.GetParticipants(ctx, travelID)
+	if err != nil {
+		return err
+	}
+	return settlement.CheckMembers(expense, participants)
+}
@@ -9,6 +9,11 @@ type TravelHandler interface {
 	UpdateTravel(w http.ResponseWriter, r *http.Request)
 	DeleteTravel(w http.ResponseWriter, r *http.Request)
 	GetAllTravels(w http.ResponseWriter, r *http.Request)
+	GetTravelGeoJSON(w http.ResponseWriter, r *http.Request)
+	ExportGPX(w http.ResponseWriter, r *http.Request)
+	ExportKML(w http.ResponseWriter, r *http.Request)
+	ImportGPX(w http.ResponseWriter, r *http.Request)
+	GetTracks(w http.ResponseWriter, r *http.Request)
 }
 
 type PlaceHandler interface {
@@ -21,7 +26,88 @@ type PlaceHandler interface {
 
 type ExpensesHandler interface {
 	CreateExpense(w http.ResponseWriter, r *http.Request)
+	CreateLegExpense(w http.ResponseWriter, r *http.Request)
 	GetExpense(w http.ResponseWriter, r *http.Request)
 	UpdateExpense(w http.ResponseWriter, r *http.Request)
 	DeleteExpense(w http.ResponseWriter, r *http.Request)
 }
+
+type ParticipantsHandler interface {
+	CreateParticipant(w http.ResponseWriter, r *http.Request)
+	GetParticipants(w http.ResponseWriter, r *http.Request)
+	DeleteParticipant(w http.ResponseWriter, r *http.Request)
+	GetBalances(w http.ResponseWriter, r *http.Request)
+}
+
+type LegHandler interface {
+	CreateLeg(w http.ResponseWriter, r *http.Request)
+	GetLegs(w http.ResponseWriter, r *http.Request)
+	UpdateLeg(w http.ResponseWriter, r *http.Request)
+	DeleteLeg(w http.ResponseWriter, r *http.Request)
+}
+
+type MemberHandler interface {
+	GetMembers(w http.ResponseWriter, r *http.Request)
+	CreateInvite(w http.ResponseWriter, r *http.Request)
+	AcceptInvite(w http.ResponseWriter, r *http.Request)
+	UpdateMemberRole(w http.ResponseWriter, r *http.Request)
+	DeleteMember(w http.ResponseWriter, r *http.Request)
+}
+
+type ShareHandler interface {
+	CreateShare(w http.ResponseWriter, r *http.Request)
+	GetShares(w http.ResponseWriter, r *http.Request)
+	RevokeShare(w http.ResponseWriter, r *http.Request)
+	GetSharedTravel(w http.ResponseWriter, r *http.Request)
+}
+
+type APIKeyHandler interface {
+	CreateAPIKey(w http.ResponseWriter, r *http.Request)
+	GetAPIKeys(w http.ResponseWriter, r *http.Request)
+	RevokeAPIKey(w http.ResponseWriter, r *http.Request)
+}
+
+type AuthHandler interface {
+	Register(w http.ResponseWriter, r *http.Request)
+	Login(w http.ResponseWriter, r *http.Request)
+	Refresh(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+	Me(w http.ResponseWriter, r *http.Request)
+}
+
+type AuditHandler interface {
+	GetHistory(w http.ResponseWriter, r *http.Request)
+}
+
+type TrashHandler interface {
+	GetTrashedTravels(w http.ResponseWriter, r *http.Request)
+	GetTrashedPlaces(w http.ResponseWriter, r *http.Request)
+	RestoreTravel(w http.ResponseWriter, r *http.Request)
+	RestorePlace(w http.ResponseWriter, r *http.Request)
+}
+
+type RevisionHandler interface {
+	GetTravelRevisions(w http.ResponseWriter, r *http.Request)
+	GetTravelRevisionDiff(w http.ResponseWriter, r *http.Request)
+	RestoreTravelRevision(w http.ResponseWriter, r *http.Request)
+	GetPlaceRevisions(w http.ResponseWriter, r *http.Request)
+	GetPlaceRevisionDiff(w http.ResponseWriter, r *http.Request)
+	RestorePlaceRevision(w http.ResponseWriter, r *http.Request)
+}
+
+type CommentHandler interface {
+	GetTravelComments(w http.ResponseWriter, r *http.Request)
+	CreateTravelComment(w http.ResponseWriter, r *http.Request)
+	GetPlaceComments(w http.ResponseWriter, r *http.Request)
+	CreatePlaceComment(w http.ResponseWriter, r *http.Request)
+	UpdateComment(w http.ResponseWriter, r *http.Request)
+	DeleteComment(w http.ResponseWriter, r *http.Request)
+	AddReaction(w http.ResponseWriter, r *http.Request)
+	DeleteReaction(w http.ResponseWriter, r *http.Request)
+}
+
+type HealthHandler interface {
+	Healthz(w http.ResponseWriter, r *http.Request)
+	Readyz(w http.ResponseWriter, r *http.Request)
+	Version(w http.ResponseWriter, r *http.Request)
+}
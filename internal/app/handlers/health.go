@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"lts/internal/app/buildinfo"
+	"lts/internal/app/ds"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+	"os"
+	"time"
+)
+
+// checkTimeout ограничивает каждую проверку готовности, чтобы зависшая БД не задерживала пробу
+const checkTimeout = 2 * time.Second
+
+type HealthHandlerImplemented struct {
+	HealthHandler
+}
+
+type HealthHandlerImpl struct {
+	HealthRepo     repository.HealthRepository
+	StorageRoot    string
+	ExpectedSchema int64
+}
+
+func NewHealthHandlerImpl(healthRepo repository.HealthRepository, storageRoot string, expectedSchema int64) *HealthHandlerImpl {
+	return &HealthHandlerImpl{
+		HealthRepo:     healthRepo,
+		StorageRoot:    storageRoot,
+		ExpectedSchema: expectedSchema,
+	}
+}
+
+// Healthz godoc
+// @Summary      Liveness probe
+// @Description  Report that the process is running and serving requests. Does not check dependencies
+// @Tags         Health
+// @Produce      plain
+// @Success      200 "ok"
+// @Router       /healthz [get]
+func (hh HealthHandlerImpl) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(ds.HealthOK))
+}
+
+// Readyz godoc
+// @Summary      Readiness probe
+// @Description  Check that Postgres answers, the image storage is writable and the database schema is at the version this build expects
+// @Tags         Health
+// @Produce      json
+// @Success      200 {object} ds.Readiness "Ready to serve requests"
+// @Failure      503 {object} ds.Readiness "Some checks failed"
+// @Router       /readyz [get]
+func (hh HealthHandlerImpl) Readyz(w http.ResponseWriter, r *http.Request) {
+	readiness := ds.Readiness{Status: ds.HealthOK, Checks: map[string]string{}}
+
+	check := func(name string, fn func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		err := fn(ctx)
+		if err != nil {
+			logging.FromContext(r.Context()).Warnw("readiness check failed", "check", name, "error", err)
+			readiness.Status = ds.HealthUnavailable
+			readiness.Checks[name] = ds.HealthUnavailable
+			return
+		}
+		readiness.Checks[name] = ds.HealthOK
+	}
+
+	check("postgres", hh.HealthRepo.Ping)
+	check("storage", hh.storageWritable)
+	check("migrations", hh.schemaCurrent)
+
+	status := http.StatusOK
+	if readiness.Status != ds.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(readiness)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorw("[json.Encode]", "error", err)
+	}
+}
+
+// Version godoc
+// @Summary      Build information
+// @Description  Get the version, git commit and build time of the running service, the applied database schema version and the version it expects
+// @Tags         Health
+// @Produce      json
+// @Success      200 {object} ds.Version "Build information"
+// @Router       /version [get]
+func (hh HealthHandlerImpl) Version(w http.ResponseWriter, r *http.Request) {
+	version := ds.Version{
+		Info:                  buildinfo.Get(),
+		ExpectedSchemaVersion: hh.ExpectedSchema,
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	schema, err := hh.HealthRepo.SchemaVersion(ctx)
+	if err != nil {
+		logging.FromContext(r.Context()).Warnw("[HealthRepo.SchemaVersion]", "error", err)
+	} else {
+		version.SchemaVersion = &schema
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(version)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// storageWritable создаёт и удаляет пустой файл в каталоге изображений
+func (hh HealthHandlerImpl) storageWritable(context.Context) error {
+	file, err := os.CreateTemp(hh.StorageRoot, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("[os.CreateTemp]: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("[file.Close]: %w", err)
+	}
+
+	err = os.Remove(file.Name())
+	if err != nil {
+		return fmt.Errorf("[os.Remove]: %w", err)
+	}
+	return nil
+}
+
+// schemaCurrent проверяет, что применены все миграции, которые знает эта сборка, и не применено лишних
+func (hh HealthHandlerImpl) schemaCurrent(ctx context.Context) error {
+	schema, err := hh.HealthRepo.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if schema != hh.ExpectedSchema {
+		return fmt.Errorf("schema version %d, expected %d", schema, hh.ExpectedSchema)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/ds"
+	"lts/internal/app/geo"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+)
+
+var errNotConsecutive = errors.New("leg must connect two consecutive places of the travel")
+
+type LegHandlerImplemented struct {
+	LegHandler
+}
+
+type LegHandlerImpl struct {
+	LegRepo      repository.LegRepository
+	TravelRepo   repository.TravelRepository
+	PlaceRepo    repository.PlaceRepository
+	ExpensesRepo repository.ExpensesRepository
+}
+
+func NewLegHandlerImpl(legRepo repository.LegRepository, travelRepo repository.TravelRepository, placeRepo repository.PlaceRepository, expensesRepo repository.ExpensesRepository) *LegHandlerImpl {
+	return &LegHandlerImpl{
+		LegRepo:      legRepo,
+		TravelRepo:   travelRepo,
+		PlaceRepo:    placeRepo,
+		ExpensesRepo: expensesRepo,
+	}
+}
+
+// CreateLeg godoc
+// @Summary      Create a transport leg
+// @Description  Create a leg (flight, train, drive, ...) between two consecutive places of the travel
+// @Tags         Legs
+// @Accept       json
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        leg body ds.Leg true "Leg details"
+// @Success      201 {object} ds.Leg "Successfully created leg"
+// @Failure      400 "Invalid UUID format or leg data"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/legs [post]
+func (lh LegHandlerImpl) CreateLeg(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var leg ds.Leg
+
+	err = json.NewDecoder(r.Body).Decode(&leg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	leg.TravelID = travelUUID
+	// Расход привязывается только через CreateLegExpense: чужой id расхода из тела запроса открыл бы к нему доступ
+	leg.Expenses = uuid.Nil
+
+	err = leg.Validate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = lh.prepareLeg(r.Context(), &leg)
+	if errors.Is(err, errNotConsecutive) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	leg, err = lh.LegRepo.CreateLeg(r.Context(), leg)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	leg.Localize()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(leg)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// GetLegs godoc
+// @Summary      Get travel legs
+// @Description  Retrieve all transport legs of the travel ordered by departure
+// @Tags         Legs
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {array} ds.FullLeg "Successfully retrieved legs"
+// @Failure      400 "Invalid UUID format"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/legs [get]
+func (lh LegHandlerImpl) GetLegs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	legs, err := lh.LegRepo.GetLegs(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	fullLegs, err := fullLegs(r.Context(), lh.ExpensesRepo, legs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(fullLegs)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// UpdateLeg godoc
+// @Summary      Update a transport leg
+// @Description  Update the details of a specific leg
+// @Tags         Legs
+// @Accept       json
+// @Produce      json
+// @Param        uuid path string true "UUID of the leg"
+// @Param        leg body ds.Leg true "Leg details"
+// @Success      200 "Successfully updated leg"
+// @Failure      400 "Invalid UUID format or leg data"
+// @Failure      500 "Internal server error"
+// @Router       /leg/{uuid} [put]
+func (lh LegHandlerImpl) UpdateLeg(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	UUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current, err := lh.LegRepo.GetLeg(r.Context(), UUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var leg ds.Leg
+
+	err = json.NewDecoder(r.Body).Decode(&leg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	leg.TravelID = current.TravelID
+	// Расход перемещения не меняется через UpdateLeg, см. CreateLeg
+	leg.Expenses = current.Expenses
+
+	err = leg.Validate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = lh.prepareLeg(r.Context(), &leg)
+	if errors.Is(err, errNotConsecutive) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = lh.LegRepo.UpdateLeg(r.Context(), UUID, leg)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteLeg godoc
+// @Summary      Delete a transport leg
+// @Description  Delete a specific leg together with its expense
+// @Tags         Legs
+// @Produce      json
+// @Param        uuid path string true "UUID of the leg"
+// @Success      200 "Successfully deleted leg"
+// @Failure      400 "Invalid UUID format"
+// @Failure      500 "Internal server error"
+// @Router       /leg/{uuid} [delete]
+func (lh LegHandlerImpl) DeleteLeg(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	UUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = lh.LegRepo.DeleteLeg(r.Context(), UUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// prepareLeg проверяет, что места идут в путешествии подряд (по дате посещения),
+// и, если расстояние не указано, подставляет расстояние по прямой между ними
+func (lh LegHandlerImpl) prepareLeg(ctx context.Context, leg *ds.Leg) error {
+	travel, err := lh.TravelRepo.GetTravel(ctx, leg.TravelID)
+	if err != nil {
+		return err
+	}
+
+	places := make([]ds.Place, 0, len(travel.Places))
+	for _, placeUUID := range travel.Places {
+		place, err := lh.PlaceRepo.GetPlace(ctx, placeUUID)
+		if err != nil {
+			return err
+		}
+		places = append(places, place)
+	}
+
+	ds.SortByVisit(places)
+
+	for i := 0; i+1 < len(places); i++ {
+		from, to := places[i], places[i+1]
+		if from.ID != leg.FromPlace || to.ID != leg.ToPlace {
+			continue
+		}
+
+		if leg.Distance == nil && from.HasLocation() && to.HasLocation() {
+			distance := geo.Distance(*from.Latitude, *from.Longitude, *to.Latitude, *to.Longitude)
+			leg.Distance = &distance
+		}
+		return nil
+	}
+
+	return errNotConsecutive
+}
+
+// fullLegs подставляет в перемещения их расходы
+func fullLegs(ctx context.Context, expensesRepo repository.ExpensesRepository, legs []ds.Leg) ([]ds.FullLeg, error) {
+	result := make([]ds.FullLeg, 0, len(legs))
+
+	for _, leg := range legs {
+		fullLeg := ds.FullLeg{
+			ID:          leg.ID,
+			FromPlace:   leg.FromPlace,
+			ToPlace:     leg.ToPlace,
+			Mode:        leg.Mode,
+			Carrier:     leg.Carrier,
+			DepartureAt: leg.DepartureAt,
+			DepartureTZ: leg.DepartureTZ,
+			ArrivalAt:   leg.ArrivalAt,
+			ArrivalTZ:   leg.ArrivalTZ,
+			BookingRef:  leg.BookingRef,
+			Distance:    leg.Distance,
+		}
+
+		if leg.Expenses != uuid.Nil {
+			expense, err := expensesRepo.GetExpense(ctx, leg.Expenses)
+			if err != nil {
+				return nil, err
+			}
+			fullLeg.Expenses = &expense
+		}
+
+		result = append(result, fullLeg)
+	}
+
+	return result, nil
+}
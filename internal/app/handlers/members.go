@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+	"net/mail"
+	"time"
+)
+
+// inviteTTL - время, в течение которого приглашение можно принять
+const inviteTTL = 7 * 24 * time.Hour
+
+type MemberHandlerImplemented struct {
+	MemberHandler
+}
+
+type MemberHandlerImpl struct {
+	MemberRepo repository.MemberRepository
+}
+
+func NewMemberHandlerImpl(memberRepo repository.MemberRepository) *MemberHandlerImpl {
+	return &MemberHandlerImpl{
+		MemberRepo: memberRepo,
+	}
+}
+
+// GetMembers godoc
+// @Summary      Get travel members
+// @Description  Retrieve users who have access to the travel and their roles
+// @Tags         Members
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {array} ds.Member "Successfully retrieved members"
+// @Failure      400 "Invalid UUID format"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/members [get]
+func (mh MemberHandlerImpl) GetMembers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	members, err := mh.MemberRepo.GetMembers(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(members)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// CreateInvite godoc
+// @Summary      Invite a user to the travel
+// @Description  Create an invite for the email with the given role. The token is returned only once
+// @Tags         Members
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        invite body ds.InviteRequest true "Email and role (owner, editor or viewer)"
+// @Success      201 {object} ds.Invite "Successfully created invite"
+// @Failure      400 "Invalid UUID format, email or role"
+// @Failure      403 "Only owners can invite"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/invites [post]
+func (mh MemberHandlerImpl) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request ds.InviteRequest
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = mail.ParseAddress(request.Email)
+	if err != nil {
+		http.Error(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	if !request.Role.Valid() {
+		http.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	invite, err := mh.MemberRepo.CreateInvite(r.Context(), ds.Invite{
+		TravelID:  travelUUID,
+		Email:     request.Email,
+		Role:      request.Role,
+		ExpiresAt: time.Now().Add(inviteTTL),
+	}, hash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	invite.Token = token
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(invite)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// AcceptInvite godoc
+// @Summary      Accept an invite
+// @Description  Join the travel using an invite token. The invite must be addressed to the current user's email
+// @Tags         Members
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        invite body ds.AcceptInvite true "Invite token"
+// @Success      200 {object} ds.Invite "Successfully joined the travel"
+// @Failure      400 "Invalid request body"
+// @Failure      404 "Invite not found, expired or addressed to another user"
+// @Failure      409 "Already a member of the travel"
+// @Failure      500 "Internal server error"
+// @Router       /invites/accept [post]
+func (mh MemberHandlerImpl) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	var request ds.AcceptInvite
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+
+	invite, err := mh.MemberRepo.AcceptInvite(r.Context(), auth.HashToken(request.Token))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(invite)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// UpdateMemberRole godoc
+// @Summary      Change member role
+// @Description  Change the role of a travel member. The travel must keep at least one owner
+// @Tags         Members
+// @Security     BearerAuth
+// @Accept       json
+// @Param        travel_uuid path string true "UUID of the travel"
+// @Param        user_uuid path string true "UUID of the user"
+// @Param        role body ds.RoleUpdate true "New role"
+// @Success      200 "Successfully changed role"
+// @Failure      400 "Invalid UUID format or role"
+// @Failure      403 "Only owners can manage members"
+// @Failure      404 "Travel or member not found"
+// @Failure      409 "The last owner cannot be demoted"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{travel_uuid}/members/{user_uuid} [put]
+func (mh MemberHandlerImpl) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	travelUUID, userUUID, err := mh.memberVars(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request ds.RoleUpdate
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !request.Role.Valid() {
+		http.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+
+	err = mh.MemberRepo.UpdateMemberRole(r.Context(), travelUUID, userUUID, request.Role)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteMember godoc
+// @Summary      Remove a member
+// @Description  Remove a member from the travel. Owners can remove anyone, other members only themselves
+// @Tags         Members
+// @Security     BearerAuth
+// @Param        travel_uuid path string true "UUID of the travel"
+// @Param        user_uuid path string true "UUID of the user"
+// @Success      200 "Successfully removed member"
+// @Failure      400 "Invalid UUID format"
+// @Failure      403 "Only owners can remove other members"
+// @Failure      404 "Travel or member not found"
+// @Failure      409 "The last owner cannot be removed"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{travel_uuid}/members/{user_uuid} [delete]
+func (mh MemberHandlerImpl) DeleteMember(w http.ResponseWriter, r *http.Request) {
+	travelUUID, userUUID, err := mh.memberVars(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = mh.MemberRepo.DeleteMember(r.Context(), travelUUID, userUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (mh MemberHandlerImpl) memberVars(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	vars := mux.Vars(r)
+
+	travelUUID, err := uuid.Parse(vars["travel_uuid"])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	userUUID, err := uuid.Parse(vars["user_uuid"])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	return travelUUID, userUUID, nil
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/ds"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"lts/internal/app/settlement"
+	"net/http"
+)
+
+type ParticipantsHandlerImplemented struct {
+	ParticipantsHandler
+}
+
+type ParticipantsHandlerImpl struct {
+	ParticipantRepo repository.ParticipantRepository
+	TravelRepo      repository.TravelRepository
+	PlaceRepo       repository.PlaceRepository
+	ExpensesRepo    repository.ExpensesRepository
+	LegRepo         repository.LegRepository
+}
+
+func NewParticipantsHandlerImpl(participantRepo repository.ParticipantRepository, travelRepo repository.TravelRepository, placeRepo repository.PlaceRepository, expensesRepo repository.ExpensesRepository, legRepo repository.LegRepository) *ParticipantsHandlerImpl {
+	return &ParticipantsHandlerImpl{
+		ParticipantRepo: participantRepo,
+		TravelRepo:      travelRepo,
+		PlaceRepo:       placeRepo,
+		ExpensesRepo:    expensesRepo,
+		LegRepo:         legRepo,
+	}
+}
+
+// CreateParticipant godoc
+// @Summary      Add a participant to travel
+// @Description  Add a person who shares the expenses of the travel
+// @Tags         Participants
+// @Accept       json
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        participant body ds.Participant true "Participant details"
+// @Success      201 {object} ds.Participant "Successfully created participant"
+// @Failure      400 "Invalid UUID format or participant data"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/participants [post]
+func (ph ParticipantsHandlerImpl) CreateParticipant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var participant ds.Participant
+
+	err = json.NewDecoder(r.Body).Decode(&participant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if participant.Name == "" {
+		http.Error(w, "participant name is required", http.StatusBadRequest)
+		return
+	}
+
+	participant.TravelID = travelUUID
+
+	participant, err = ph.ParticipantRepo.CreateParticipant(r.Context(), participant)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(participant)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// GetParticipants godoc
+// @Summary      Get travel participants
+// @Description  Retrieve all participants of a specific travel
+// @Tags         Participants
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {array} ds.Participant "Successfully retrieved participants"
+// @Failure      400 "Invalid UUID format"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/participants [get]
+func (ph ParticipantsHandlerImpl) GetParticipants(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	participants, err := ph.ParticipantRepo.GetParticipants(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(participants)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// DeleteParticipant godoc
+// @Summary      Remove a participant from travel
+// @Description  Remove a participant. Participants who pay for or share expenses cannot be removed
+// @Tags         Participants
+// @Produce      json
+// @Param        travel_uuid path string true "UUID of the travel"
+// @Param        participant_uuid path string true "UUID of the participant"
+// @Success      200 "Successfully deleted participant"
+// @Failure      400 "Invalid travel UUID or participant UUID"
+// @Failure      409 "Participant still pays for or shares expenses"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{travel_uuid}/participants/{participant_uuid} [delete]
+func (ph ParticipantsHandlerImpl) DeleteParticipant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	travelStr, ok := vars["travel_uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("travel uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(travelStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	participantStr, ok := vars["participant_uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("participant uuid is missing in parameters")
+	}
+
+	participantUUID, err := uuid.Parse(participantStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ph.ParticipantRepo.DeleteParticipant(r.Context(), travelUUID, participantUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetBalances godoc
+// @Summary      Get travel balances
+// @Description  Calculate who owes whom across all expenses of the travel and the fewest transfers needed to settle up.
+// @Description  With more than 16 participants owing or owed money the transfers are not guaranteed to be the fewest,
+// @Description  but there are at most one less than such participants
+// @Tags         Participants
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {object} ds.TravelBalances "Successfully calculated balances"
+// @Failure      400 "Invalid UUID format"
+// @Failure      422 "Expense split does not match travel participants"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/balances [get]
+func (ph ParticipantsHandlerImpl) GetBalances(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	travel, err := ph.TravelRepo.GetTravel(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	participants, err := ph.ParticipantRepo.GetParticipants(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var expenses []ds.Expense
+
+	for _, placeUUID := range travel.Places {
+		place, err := ph.PlaceRepo.GetPlace(r.Context(), placeUUID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if place.Expenses == uuid.Nil {
+			continue
+		}
+
+		expense, err := ph.ExpensesRepo.GetExpense(r.Context(), place.Expenses)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		expenses = append(expenses, expense)
+	}
+
+	legs, err := ph.LegRepo.GetLegs(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	for _, leg := range legs {
+		if leg.Expenses == uuid.Nil {
+			continue
+		}
+
+		expense, err := ph.ExpensesRepo.GetExpense(r.Context(), leg.Expenses)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		expenses = append(expenses, expense)
+	}
+
+	balances, err := settlement.Balances(participants, expenses)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result := ds.TravelBalances{
+		Balances:  balances,
+		Transfers: settlement.SettleUp(balances),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		writeError(w, err)
+	}
+}
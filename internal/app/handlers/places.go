@@ -1,16 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"go.uber.org/zap"
-	"io"
 	"lts/internal/app/ds"
+	"lts/internal/app/logging"
+	"lts/internal/app/quota"
 	"lts/internal/app/repository"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 type PlaceHandlerImplemented struct {
@@ -20,11 +23,12 @@ type PlaceHandlerImplemented struct {
 type PlaceHandlerImpl struct {
 	PlaceRepo  repository.PlaceRepository
 	TravelRepo repository.TravelRepository
-	Logger     *zap.SugaredLogger
+	MemberRepo repository.MemberRepository
+	Quota      *quota.Quota
 }
 
-func NewPlaceHandlerImpl(placeRepo repository.PlaceRepository, travelRepo repository.TravelRepository, logger *zap.SugaredLogger) *PlaceHandlerImpl {
-	return &PlaceHandlerImpl{PlaceRepo: placeRepo, TravelRepo: travelRepo, Logger: logger}
+func NewPlaceHandlerImpl(placeRepo repository.PlaceRepository, travelRepo repository.TravelRepository, memberRepo repository.MemberRepository, storageQuota *quota.Quota) *PlaceHandlerImpl {
+	return &PlaceHandlerImpl{PlaceRepo: placeRepo, TravelRepo: travelRepo, MemberRepo: memberRepo, Quota: storageQuota}
 }
 
 // CreatePlace godoc
@@ -43,7 +47,7 @@ func (ph PlaceHandlerImpl) CreatePlace(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	travelStr, ok := vars["travel_uuid"]
 	if !ok {
-		ph.Logger.Info("travel uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("travel uuid is missing in parameters")
 	}
 
 	travelUUID, err := uuid.Parse(travelStr)
@@ -61,15 +65,29 @@ func (ph PlaceHandlerImpl) CreatePlace(w http.ResponseWriter, r *http.Request) {
 
 	}
 
+	place.CountryCode = strings.ToUpper(place.CountryCode)
+
+	err = place.ValidateLocation()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = requireRole(r.Context(), ph.MemberRepo.GetRole, travelUUID, ds.RoleEditor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
 	place, err = ph.PlaceRepo.CreatePlace(r.Context(), place)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	err = ph.TravelRepo.AddPlace(r.Context(), travelUUID, place.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -77,7 +95,7 @@ func (ph PlaceHandlerImpl) CreatePlace(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	err = json.NewEncoder(w).Encode(place)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 	}
 }
 
@@ -98,7 +116,7 @@ func (ph PlaceHandlerImpl) SetPreview(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	travelStr, ok := vars["travel_uuid"]
 	if !ok {
-		ph.Logger.Info("travel uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("travel uuid is missing in parameters")
 	}
 
 	travelUUID, err := uuid.Parse(travelStr)
@@ -109,7 +127,7 @@ func (ph PlaceHandlerImpl) SetPreview(w http.ResponseWriter, r *http.Request) {
 
 	placeStr, ok := vars["place_uuid"]
 	if !ok {
-		ph.Logger.Info("place uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("place uuid is missing in parameters")
 	}
 
 	placeUUID, err := uuid.Parse(placeStr)
@@ -118,30 +136,35 @@ func (ph PlaceHandlerImpl) SetPreview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = os.MkdirAll(fmt.Sprintf("./images/travel/%s/places/%s", travelUUID, placeUUID), os.ModePerm)
+	err = ph.checkPlace(r.Context(), travelUUID, placeUUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	path := fmt.Sprintf("./images/travel/%s/places/%s/preview.jpg", travelUUID, placeUUID)
+	_, err = reserveUpload(w, r, ph.Quota, ph.TravelRepo, travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-	file, err := os.Create(path)
+	err = os.MkdirAll(fmt.Sprintf("%s/%s/places/%s", ph.Quota.Root, travelUUID, placeUUID), os.ModePerm)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, r.Body)
+	path := fmt.Sprintf("%s/%s/places/%s/preview.jpg", ph.Quota.Root, travelUUID, placeUUID)
+
+	err = saveUpload(r, ph.Quota, ph.TravelRepo, travelUUID, placeUUID, path, r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	err = ph.PlaceRepo.SetPreview(r.Context(), path, placeUUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -166,7 +189,7 @@ func (ph PlaceHandlerImpl) SetImages(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	travelStr, ok := vars["travel_uuid"]
 	if !ok {
-		ph.Logger.Info("travel uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("travel uuid is missing in parameters")
 	}
 
 	travelUUID, err := uuid.Parse(travelStr)
@@ -177,7 +200,7 @@ func (ph PlaceHandlerImpl) SetImages(w http.ResponseWriter, r *http.Request) {
 
 	placeStr, ok := vars["place_uuid"]
 	if !ok {
-		ph.Logger.Info("place uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("place uuid is missing in parameters")
 	}
 
 	placeUUID, err := uuid.Parse(placeStr)
@@ -186,11 +209,25 @@ func (ph PlaceHandlerImpl) SetImages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var paths []string
+	err = ph.checkPlace(r.Context(), travelUUID, placeUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-	ph.Logger.Info(r.MultipartForm)
+	maxUpload, err := reserveUpload(w, r, ph.Quota, ph.TravelRepo, travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-	err = r.ParseMultipartForm(10 << 20)
+	var paths []string
+
+	err = r.ParseMultipartForm(maxUpload)
+	if tooLarge(err) {
+		writeError(w, err)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Ошибка при парсинге формы: "+err.Error(), http.StatusBadRequest)
 		return
@@ -210,38 +247,31 @@ func (ph PlaceHandlerImpl) SetImages(w http.ResponseWriter, r *http.Request) {
 	for _, fileHeader := range files {
 		file, err := fileHeader.Open()
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 		defer file.Close()
 
-		err = os.MkdirAll(fmt.Sprintf("./images/travel/%s/places/%s/images", travelUUID, placeUUID), os.ModePerm)
+		err = os.MkdirAll(fmt.Sprintf("%s/%s/places/%s/images", ph.Quota.Root, travelUUID, placeUUID), os.ModePerm)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
-		path := fmt.Sprintf("./images/travel/%s/places/%s/images/%s", travelUUID, placeUUID, fileHeader.Filename)
+		path := fmt.Sprintf("%s/%s/places/%s/images/%s", ph.Quota.Root, travelUUID, placeUUID, filepath.Base(fileHeader.Filename))
 
 		paths = append(paths, path)
 
-		out, err := os.Create(path)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		defer file.Close()
-
-		_, err = io.Copy(out, file)
+		err = saveUpload(r, ph.Quota, ph.TravelRepo, travelUUID, placeUUID, path, file)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 	}
 
 	err = ph.PlaceRepo.SetImages(r.Context(), paths, placeUUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -250,7 +280,7 @@ func (ph PlaceHandlerImpl) SetImages(w http.ResponseWriter, r *http.Request) {
 
 // DeletePlace godoc
 // @Summary      Delete a place
-// @Description  Delete a specific place associated with a travel, including all associated data and images
+// @Description  Move the place to the trash. Its expenses and images are kept until the trash is purged
 // @Tags         Places
 // @Produce      json
 // @Param        travel_uuid path string true "UUID of the travel"
@@ -263,7 +293,7 @@ func (ph PlaceHandlerImpl) DeletePlace(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	travelStr, ok := vars["travel_uuid"]
 	if !ok {
-		ph.Logger.Info("travel uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("travel uuid is missing in parameters")
 	}
 
 	travelUUID, err := uuid.Parse(travelStr)
@@ -274,7 +304,7 @@ func (ph PlaceHandlerImpl) DeletePlace(w http.ResponseWriter, r *http.Request) {
 
 	placeStr, ok := vars["place_uuid"]
 	if !ok {
-		ph.Logger.Info("place uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("place uuid is missing in parameters")
 	}
 
 	placeUUID, err := uuid.Parse(placeStr)
@@ -283,17 +313,15 @@ func (ph PlaceHandlerImpl) DeletePlace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := fmt.Sprintf("./images/travel/%s/places/%s", travelUUID, placeUUID)
-
-	err = os.RemoveAll(path)
+	err = ph.checkPlace(r.Context(), travelUUID, placeUUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	err = ph.PlaceRepo.DeletePlace(r.Context(), placeUUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -316,7 +344,7 @@ func (ph PlaceHandlerImpl) UpdatePlace(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		ph.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	UUID, err := uuid.Parse(uuidStr)
@@ -333,11 +361,41 @@ func (ph PlaceHandlerImpl) UpdatePlace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	place.CountryCode = strings.ToUpper(place.CountryCode)
+
+	err = place.ValidateLocation()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	err = ph.PlaceRepo.UpdatePlace(r.Context(), UUID, place)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// checkPlace проверяет, что место принадлежит путешествию, которое пользователь может редактировать.
+// Вызывается до работы с файлами, чтобы не трогать каталоги чужих путешествий
+func (ph PlaceHandlerImpl) checkPlace(ctx context.Context, travelUUID, placeUUID uuid.UUID) error {
+	err := requireRole(ctx, ph.MemberRepo.GetRole, travelUUID, ds.RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	travel, err := ph.TravelRepo.GetTravel(ctx, travelUUID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range travel.Places {
+		if id == placeUUID {
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
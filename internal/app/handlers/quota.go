@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"lts/internal/app/helpers"
+	"lts/internal/app/quota"
+	"lts/internal/app/repository"
+	"net/http"
+	"strconv"
+)
+
+// defaultMultipartMemory - сколько формы держать в памяти без quota.max_upload, как в http.Request.FormFile
+const defaultMultipartMemory = 32 << 20
+
+// reserveUpload проверяет размер загрузки и квоту создателя путешествия до записи файлов,
+// ограничивает тело запроса и добавляет в ответ заголовки квоты.
+// Если размер тела неизвестен, он считается максимально допустимым.
+// Возвращает лимит тела запроса, с которым нужно разбирать multipart-форму
+func reserveUpload(w http.ResponseWriter, r *http.Request, q *quota.Quota, travelRepo repository.TravelRepository, travelUUID uuid.UUID) (int64, error) {
+	used, err := travelRepo.GetStorageUsage(r.Context(), travelUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	limits := q.Limits()
+
+	if limits.UserStorage > 0 {
+		w.Header().Set("X-Quota-Limit", strconv.FormatInt(limits.UserStorage, 10))
+		w.Header().Set("X-Quota-Used", strconv.FormatInt(used, 10))
+	}
+
+	size := r.ContentLength
+	if size < 0 {
+		size = limits.MaxUpload
+	}
+
+	if limits.MaxUpload <= 0 {
+		return defaultMultipartMemory, q.Check(used, size)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxUpload)
+	return limits.MaxUpload, q.Check(used, size)
+}
+
+// saveUpload записывает загруженный файл и учитывает его в квоте создателя путешествия
+func saveUpload(r *http.Request, q *quota.Quota, travelRepo repository.TravelRepository, travelUUID, placeUUID uuid.UUID, path string, src io.Reader) error {
+	written, err := helpers.SaveImage(r.Context(), path, src)
+	if err != nil {
+		return err
+	}
+
+	file, err := q.File(path, travelUUID, placeUUID, written)
+	if err != nil {
+		return err
+	}
+	return travelRepo.AddFile(r.Context(), file)
+}
+
+// tooLarge - превышен размер загрузки или квота
+func tooLarge(err error) bool {
+	var maxBytes *http.MaxBytesError
+	return errors.As(err, &maxBytes) || errors.Is(err, quota.ErrTooLarge) || errors.Is(err, quota.ErrExceeded)
+}
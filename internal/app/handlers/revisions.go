@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/ds"
+	"lts/internal/app/repository"
+	"lts/internal/app/textdiff"
+	"net/http"
+	"strconv"
+)
+
+type RevisionHandlerImplemented struct {
+	RevisionHandler
+}
+
+type RevisionHandlerImpl struct {
+	RevisionRepo repository.RevisionRepository
+}
+
+func NewRevisionHandlerImpl(revisionRepo repository.RevisionRepository) *RevisionHandlerImpl {
+	return &RevisionHandlerImpl{
+		RevisionRepo: revisionRepo,
+	}
+}
+
+// GetTravelRevisions godoc
+// @Summary      Get travel description revisions
+// @Description  Retrieve saved versions of the travel description, newest first
+// @Tags         Revisions
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {array} ds.Revision "Successfully retrieved revisions"
+// @Failure      400 "Invalid UUID format"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/revisions [get]
+func (rh RevisionHandlerImpl) GetTravelRevisions(w http.ResponseWriter, r *http.Request) {
+	rh.getRevisions(w, r, ds.RevisionTravel)
+}
+
+// GetTravelRevisionDiff godoc
+// @Summary      Compare travel description revisions
+// @Description  Get a unified diff between two revisions of the travel description
+// @Tags         Revisions
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        from query int true "Older revision id"
+// @Param        to query int true "Newer revision id"
+// @Success      200 {object} ds.RevisionDiff "Successfully compared revisions"
+// @Failure      400 "Invalid UUID format or revision ids"
+// @Failure      404 "Travel or revision not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/revisions/diff [get]
+func (rh RevisionHandlerImpl) GetTravelRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	rh.getRevisionDiff(w, r, ds.RevisionTravel)
+}
+
+// RestoreTravelRevision godoc
+// @Summary      Restore travel description revision
+// @Description  Make the text of the revision the current travel description. The restore is saved as a new revision
+// @Tags         Revisions
+// @Security     BearerAuth
+// @Param        uuid path string true "UUID of the travel"
+// @Param        revision_id path int true "Revision id"
+// @Success      200 "Successfully restored revision"
+// @Failure      400 "Invalid UUID format or revision id"
+// @Failure      404 "Travel or revision not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/revisions/{revision_id}/restore [post]
+func (rh RevisionHandlerImpl) RestoreTravelRevision(w http.ResponseWriter, r *http.Request) {
+	rh.restoreRevision(w, r, ds.RevisionTravel)
+}
+
+// GetPlaceRevisions godoc
+// @Summary      Get place story revisions
+// @Description  Retrieve saved versions of the place story, newest first
+// @Tags         Revisions
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the place"
+// @Success      200 {array} ds.Revision "Successfully retrieved revisions"
+// @Failure      400 "Invalid UUID format"
+// @Failure      404 "Place not found"
+// @Failure      500 "Internal server error"
+// @Router       /place/{uuid}/revisions [get]
+func (rh RevisionHandlerImpl) GetPlaceRevisions(w http.ResponseWriter, r *http.Request) {
+	rh.getRevisions(w, r, ds.RevisionPlace)
+}
+
+// GetPlaceRevisionDiff godoc
+// @Summary      Compare place story revisions
+// @Description  Get a unified diff between two revisions of the place story
+// @Tags         Revisions
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the place"
+// @Param        from query int true "Older revision id"
+// @Param        to query int true "Newer revision id"
+// @Success      200 {object} ds.RevisionDiff "Successfully compared revisions"
+// @Failure      400 "Invalid UUID format or revision ids"
+// @Failure      404 "Place or revision not found"
+// @Failure      500 "Internal server error"
+// @Router       /place/{uuid}/revisions/diff [get]
+func (rh RevisionHandlerImpl) GetPlaceRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	rh.getRevisionDiff(w, r, ds.RevisionPlace)
+}
+
+// RestorePlaceRevision godoc
+// @Summary      Restore place story revision
+// @Description  Make the text of the revision the current place story. The restore is saved as a new revision
+// @Tags         Revisions
+// @Security     BearerAuth
+// @Param        uuid path string true "UUID of the place"
+// @Param        revision_id path int true "Revision id"
+// @Success      200 "Successfully restored revision"
+// @Failure      400 "Invalid UUID format or revision id"
+// @Failure      404 "Place or revision not found"
+// @Failure      500 "Internal server error"
+// @Router       /place/{uuid}/revisions/{revision_id}/restore [post]
+func (rh RevisionHandlerImpl) RestorePlaceRevision(w http.ResponseWriter, r *http.Request) {
+	rh.restoreRevision(w, r, ds.RevisionPlace)
+}
+
+func (rh RevisionHandlerImpl) getRevisions(w http.ResponseWriter, r *http.Request, entity ds.RevisionEntity) {
+	id, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := rh.RevisionRepo.GetRevisions(r.Context(), entity, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(revisions)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (rh RevisionHandlerImpl) getRevisionDiff(w http.ResponseWriter, r *http.Request, entity ds.RevisionEntity) {
+	id, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fromID, err := revisionID(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	toID, err := revisionID(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, err := rh.RevisionRepo.GetRevision(r.Context(), entity, id, fromID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	to, err := rh.RevisionRepo.GetRevision(r.Context(), entity, id, toID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	diff := ds.RevisionDiff{
+		From: from.ID,
+		To:   to.ID,
+		Diff: textdiff.Unified(from.Body, to.Body, textdiff.DefaultContext),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(diff)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (rh RevisionHandlerImpl) restoreRevision(w http.ResponseWriter, r *http.Request, entity ds.RevisionEntity) {
+	vars := mux.Vars(r)
+
+	id, err := uuid.Parse(vars["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revision, err := revisionID(vars["revision_id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = rh.RevisionRepo.RestoreRevision(r.Context(), entity, id, revision)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func revisionID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("revision id must be a positive integer")
+	}
+	return id, nil
+}
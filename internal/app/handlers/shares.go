@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"lts/internal/app/helpers"
+	"lts/internal/app/logging"
+	"lts/internal/app/repository"
+	"net/http"
+	"time"
+)
+
+type ShareHandlerImplemented struct {
+	ShareHandler
+}
+
+type ShareHandlerImpl struct {
+	ShareRepo    repository.ShareRepository
+	TravelRepo   repository.TravelRepository
+	PlaceRepo    repository.PlaceRepository
+	ExpensesRepo repository.ExpensesRepository
+	LegRepo      repository.LegRepository
+}
+
+func NewShareHandlerImpl(shareRepo repository.ShareRepository, travelRepo repository.TravelRepository, placeRepo repository.PlaceRepository, expensesRepo repository.ExpensesRepository, legRepo repository.LegRepository) *ShareHandlerImpl {
+	return &ShareHandlerImpl{
+		ShareRepo:    shareRepo,
+		TravelRepo:   travelRepo,
+		PlaceRepo:    placeRepo,
+		ExpensesRepo: expensesRepo,
+		LegRepo:      legRepo,
+	}
+}
+
+// CreateShare godoc
+// @Summary      Create a public share link
+// @Description  Create a read-only link to the travel for people without an account. The token is returned only once
+// @Tags         Shares
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        share body ds.ShareRequest true "Link options"
+// @Success      201 {object} ds.ShareLink "Successfully created link"
+// @Failure      400 "Invalid UUID format or options"
+// @Failure      403 "Only owners can share the travel"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/shares [post]
+func (sh ShareHandlerImpl) CreateShare(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request ds.ShareRequest
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	share, err := sh.ShareRepo.CreateShare(r.Context(), ds.ShareLink{
+		TravelID:      travelUUID,
+		HideExpenses:  request.HideExpenses,
+		StripLocation: request.StripLocation,
+		ExpiresAt:     request.ExpiresAt,
+	}, hash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	share.Token = token
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(share)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// GetShares godoc
+// @Summary      Get active share links
+// @Description  Retrieve share links of the travel that are neither revoked nor expired
+// @Tags         Shares
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {array} ds.ShareLink "Successfully retrieved links"
+// @Failure      400 "Invalid UUID format"
+// @Failure      403 "Only owners can manage share links"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/shares [get]
+func (sh ShareHandlerImpl) GetShares(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	travelUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shares, err := sh.ShareRepo.GetShares(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(shares)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// RevokeShare godoc
+// @Summary      Revoke a share link
+// @Description  Revoke a share link. The link stops working immediately
+// @Tags         Shares
+// @Security     BearerAuth
+// @Param        travel_uuid path string true "UUID of the travel"
+// @Param        share_uuid path string true "UUID of the link"
+// @Success      200 "Successfully revoked link"
+// @Failure      400 "Invalid UUID format"
+// @Failure      403 "Only owners can manage share links"
+// @Failure      404 "Travel or link not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{travel_uuid}/shares/{share_uuid} [delete]
+func (sh ShareHandlerImpl) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	travelUUID, err := uuid.Parse(vars["travel_uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shareUUID, err := uuid.Parse(vars["share_uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = sh.ShareRepo.RevokeShare(r.Context(), travelUUID, shareUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetSharedTravel godoc
+// @Summary      View a shared travel
+// @Description  Retrieve the travel by a public share link without authentication.
+// @Description  Depending on the link options, expenses are hidden and coordinates, addresses, country codes, leg distances and photo metadata are removed
+// @Tags         Shares
+// @Produce      json
+// @Param        token path string true "Share token"
+// @Success      200 {object} ds.FullTravel "Shared travel"
+// @Failure      404 "Link not found, revoked or expired"
+// @Failure      500 "Internal server error"
+// @Router       /shared/{token} [get]
+func (sh ShareHandlerImpl) GetSharedTravel(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	share, err := sh.ShareRepo.ResolveShare(r.Context(), auth.HashToken(token))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	loader := travelLoader{
+		TravelRepo:   sh.TravelRepo,
+		PlaceRepo:    sh.PlaceRepo,
+		ExpensesRepo: sh.ExpensesRepo,
+		LegRepo:      sh.LegRepo,
+		LoadImage:    helpers.LoadImage,
+	}
+	if share.StripLocation {
+		loader.LoadImage = func(ctx context.Context, path string) (string, error) {
+			image, err := helpers.LoadImageWithoutMetadata(ctx, path)
+			if errors.Is(err, helpers.ErrUnsupportedImage) {
+				// Изображение, из которого нельзя убрать координаты, по ссылке не показывается
+				logging.FromContext(ctx).Warnw("image skipped in shared travel", "path", path, "error", err)
+				return "", nil
+			}
+			return image, err
+		}
+	}
+
+	// Дальше репозитории видят ссылку как участника с ролью viewer
+	ctx := auth.WithPrincipal(r.Context(), auth.Principal{ShareID: share.ID})
+
+	travel, err := loader.load(ctx, share.TravelID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	share.Redact(&travel)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	err = json.NewEncoder(w).Encode(travel)
+	if err != nil {
+		writeError(w, err)
+	}
+}
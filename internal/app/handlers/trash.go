@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"lts/internal/app/ds"
+	"lts/internal/app/repository"
+	"net/http"
+	"time"
+)
+
+type TrashHandlerImplemented struct {
+	TrashHandler
+}
+
+type TrashHandlerImpl struct {
+	TrashRepo repository.TrashRepository
+	Retention time.Duration
+}
+
+// NewTrashHandlerImpl - retention нужен, чтобы показать, когда корзина будет очищена; 0 - не очищается
+func NewTrashHandlerImpl(trashRepo repository.TrashRepository, retention time.Duration) *TrashHandlerImpl {
+	return &TrashHandlerImpl{
+		TrashRepo: trashRepo,
+		Retention: retention,
+	}
+}
+
+// GetTrashedTravels godoc
+// @Summary      Get trashed travels
+// @Description  Retrieve deleted travels owned by the user that can still be restored
+// @Tags         Trash
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200 {array} ds.TrashItem "Successfully retrieved trashed travels"
+// @Failure      500 "Internal server error"
+// @Router       /trash [get]
+func (th TrashHandlerImpl) GetTrashedTravels(w http.ResponseWriter, r *http.Request) {
+	items, err := th.TrashRepo.GetTrashedTravels(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	th.writeItems(w, items)
+}
+
+// GetTrashedPlaces godoc
+// @Summary      Get trashed places
+// @Description  Retrieve deleted places of the travel that can still be restored
+// @Tags         Trash
+// @Security     BearerAuth
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {array} ds.TrashItem "Successfully retrieved trashed places"
+// @Failure      400 "Invalid UUID format"
+// @Failure      403 "Only editors and owners can see the trash"
+// @Failure      404 "Travel not found"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/trash [get]
+func (th TrashHandlerImpl) GetTrashedPlaces(w http.ResponseWriter, r *http.Request) {
+	travelUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := th.TrashRepo.GetTrashedPlaces(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	th.writeItems(w, items)
+}
+
+// RestoreTravel godoc
+// @Summary      Restore travel
+// @Description  Restore a deleted travel with its places, expenses and images
+// @Tags         Trash
+// @Security     BearerAuth
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 "Successfully restored travel"
+// @Failure      400 "Invalid UUID format"
+// @Failure      404 "Travel not found in the trash"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/restore [post]
+func (th TrashHandlerImpl) RestoreTravel(w http.ResponseWriter, r *http.Request) {
+	travelUUID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = th.TrashRepo.RestoreTravel(r.Context(), travelUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RestorePlace godoc
+// @Summary      Restore place
+// @Description  Restore a deleted place of the travel with its expenses and images
+// @Tags         Trash
+// @Security     BearerAuth
+// @Param        travel_uuid path string true "UUID of the travel"
+// @Param        place_uuid path string true "UUID of the place"
+// @Success      200 "Successfully restored place"
+// @Failure      400 "Invalid UUID format"
+// @Failure      404 "Place not found in the trash"
+// @Failure      500 "Internal server error"
+// @Router       /place/{travel_uuid}/{place_uuid}/restore [post]
+func (th TrashHandlerImpl) RestorePlace(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	travelUUID, err := uuid.Parse(vars["travel_uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	placeUUID, err := uuid.Parse(vars["place_uuid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = th.TrashRepo.RestorePlace(r.Context(), travelUUID, placeUUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (th TrashHandlerImpl) writeItems(w http.ResponseWriter, items []ds.TrashItem) {
+	if th.Retention > 0 {
+		for i := range items {
+			purgeAt := items[i].DeletedAt.Add(th.Retention)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(items)
+	if err != nil {
+		writeError(w, err)
+	}
+}
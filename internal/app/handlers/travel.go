@@ -1,19 +1,27 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"go.uber.org/zap"
 	"io"
 	"lts/internal/app/ds"
+	"lts/internal/app/geo"
 	"lts/internal/app/helpers"
+	"lts/internal/app/logging"
+	"lts/internal/app/quota"
 	"lts/internal/app/repository"
 	"net/http"
 	"os"
+	"time"
 )
 
+// maxGPXSize - ограничение на размер загружаемого GPX-файла
+const maxGPXSize = 32 << 20
+
 type TravelHandlerImplemented struct {
 	TravelHandler
 }
@@ -22,15 +30,21 @@ type TravelHandlerImpl struct {
 	TravelRepo   repository.TravelRepository
 	PlaceRepo    repository.PlaceRepository
 	ExpensesRepo repository.ExpensesRepository
-	Logger       *zap.SugaredLogger
+	TrackRepo    repository.TrackRepository
+	LegRepo      repository.LegRepository
+	MemberRepo   repository.MemberRepository
+	Quota        *quota.Quota
 }
 
-func NewTravelHandlerImpl(travelRepo repository.TravelRepository, placeRepo repository.PlaceRepository, expensesRepo repository.ExpensesRepository, logger *zap.SugaredLogger) *TravelHandlerImpl {
+func NewTravelHandlerImpl(travelRepo repository.TravelRepository, placeRepo repository.PlaceRepository, expensesRepo repository.ExpensesRepository, trackRepo repository.TrackRepository, legRepo repository.LegRepository, memberRepo repository.MemberRepository, storageQuota *quota.Quota) *TravelHandlerImpl {
 	return &TravelHandlerImpl{
 		TravelRepo:   travelRepo,
 		PlaceRepo:    placeRepo,
 		ExpensesRepo: expensesRepo,
-		Logger:       logger,
+		TrackRepo:    trackRepo,
+		LegRepo:      legRepo,
+		MemberRepo:   memberRepo,
+		Quota:        storageQuota,
 	}
 }
 
@@ -57,7 +71,7 @@ func (th *TravelHandlerImpl) CreateTravel(w http.ResponseWriter, r *http.Request
 
 	travel, err = th.TravelRepo.CreateTravel(r.Context(), travel)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -65,7 +79,7 @@ func (th *TravelHandlerImpl) CreateTravel(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusCreated)
 	err = json.NewEncoder(w).Encode(travel)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 	}
 }
 
@@ -85,7 +99,7 @@ func (th *TravelHandlerImpl) SetTravelPreview(w http.ResponseWriter, r *http.Req
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		th.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	uuidParsed, err := uuid.Parse(uuidStr)
@@ -94,37 +108,35 @@ func (th *TravelHandlerImpl) SetTravelPreview(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = os.MkdirAll(fmt.Sprintf("./images/travel/%s", uuidStr), os.ModePerm)
+	err = requireRole(r.Context(), th.MemberRepo.GetRole, uuidParsed, ds.RoleEditor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	path := fmt.Sprintf("./images/travel/%s/preview.jpg", uuidStr)
+	_, err = reserveUpload(w, r, th.Quota, th.TravelRepo, uuidParsed)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-	file, err := os.Create(path)
+	err = os.MkdirAll(fmt.Sprintf("%s/%s", th.Quota.Root, uuidStr), os.ModePerm)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}(file)
+	path := fmt.Sprintf("%s/%s/preview.jpg", th.Quota.Root, uuidStr)
 
-	_, err = io.Copy(file, r.Body)
+	err = saveUpload(r, th.Quota, th.TravelRepo, uuidParsed, uuid.Nil, path, r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	err = th.TravelRepo.SetTravelPreview(r.Context(), path, uuidParsed)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -145,7 +157,7 @@ func (th *TravelHandlerImpl) GetTravel(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		th.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	UUID, err := uuid.Parse(uuidStr)
@@ -154,97 +166,16 @@ func (th *TravelHandlerImpl) GetTravel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var travel ds.Travel
-
-	travel, err = th.TravelRepo.GetTravel(r.Context(), UUID)
+	fullTravel, err := th.loader(helpers.LoadImage).load(r.Context(), UUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	if travel.Preview != "" {
-		travel.Preview, err = helpers.LoadImage(travel.Preview)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-
-	var places []ds.FullPlace
-
-	for _, placeUUID := range travel.Places {
-		var place ds.Place
-		place, err = th.PlaceRepo.GetPlace(r.Context(), placeUUID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		images := place.Images
-		for i, imagePath := range images {
-			//if imagePath == "" {
-			//	continue
-			//}
-			th.Logger.Info(imagePath)
-			if imagePath != "" {
-				imageData, err := helpers.LoadImage(imagePath)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				place.Images[i] = imageData
-			}
-
-		}
-		th.Logger.Info(place.Preview)
-		if place.Preview != "" {
-			place.Preview, err = helpers.LoadImage(place.Preview)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		}
-
-		var expense ds.Expense
-
-		if place.Expenses != uuid.Nil {
-			expense, err = th.ExpensesRepo.GetExpense(r.Context(), place.Expenses)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		}
-
-		fullPlace := ds.FullPlace{
-			ID:      place.ID,
-			Name:    place.Name,
-			Story:   place.Story,
-			Date:    place.Date,
-			Images:  place.Images,
-			Preview: place.Preview,
-		}
-
-		// Записываем expense в Expenses только если place.Expenses не nil
-		if place.Expenses != uuid.Nil {
-			fullPlace.Expenses = &expense
-		}
-
-		places = append(places, fullPlace)
-	}
-
-	fullTravel := ds.FullTravel{
-		ID:          travel.ID,
-		Name:        travel.Name,
-		Description: travel.Description,
-		DateStart:   travel.DateStart,
-		DateEnd:     travel.DateEnd,
-		Places:      places,
-		Preview:     travel.Preview,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(fullTravel)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 }
@@ -265,7 +196,7 @@ func (th *TravelHandlerImpl) UpdateTravel(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		th.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	UUID, err := uuid.Parse(uuidStr)
@@ -284,7 +215,7 @@ func (th *TravelHandlerImpl) UpdateTravel(w http.ResponseWriter, r *http.Request
 
 	err = th.TravelRepo.UpdateTravel(r.Context(), UUID, travel)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -293,19 +224,20 @@ func (th *TravelHandlerImpl) UpdateTravel(w http.ResponseWriter, r *http.Request
 
 // DeleteTravel godoc
 // @Summary      Delete travel
-// @Description  Delete a specific travel and all associated places and expenses
+// @Description  Move the travel to the trash. Places, expenses and images are kept until the trash is purged
 // @Tags         Travel
 // @Produce      json
 // @Param        uuid path string true "UUID of the travel"
 // @Success      200 "Successfully deleted travel"
 // @Failure      400 "Invalid UUID format"
+// @Failure      403 "Only owners can delete the travel"
 // @Failure      500 "Internal server error"
 // @Router       /travel/{uuid} [delete]
 func (th *TravelHandlerImpl) DeleteTravel(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uuidStr, ok := vars["uuid"]
 	if !ok {
-		th.Logger.Info("uuid is missing in parameters")
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
 	}
 
 	UUID, err := uuid.Parse(uuidStr)
@@ -314,76 +246,453 @@ func (th *TravelHandlerImpl) DeleteTravel(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var travel ds.Travel
-
-	travel, err = th.TravelRepo.GetTravel(r.Context(), UUID)
+	err = requireRole(r.Context(), th.MemberRepo.GetRole, UUID, ds.RoleOwner)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	err = th.TravelRepo.DeleteTravel(r.Context(), UUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	for _, placeUUID := range travel.Places {
-		place, err := th.PlaceRepo.GetPlace(r.Context(), placeUUID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (th *TravelHandlerImpl) GetAllTravels(w http.ResponseWriter, r *http.Request) {
+	travels, err := th.TravelRepo.GetAllTravels(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	for i := range travels {
+		t := &travels[i] // получаем указатель на объект
+		if t.Preview != "" {
+			t.Preview, err = helpers.LoadImage(r.Context(), t.Preview)
 
-		if place.Expenses != uuid.Nil {
-			err = th.ExpensesRepo.DeleteExpense(r.Context(), place.Expenses)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeError(w, err)
 				return
 			}
 		}
+	}
 
-		err = th.PlaceRepo.DeletePlace(r.Context(), placeUUID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(travels)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
+}
 
-	path := fmt.Sprintf("./images/travel/%s", UUID)
+// GetTravelGeoJSON godoc
+// @Summary      Get travel map
+// @Description  Retrieve places of the travel as a GeoJSON FeatureCollection of points plus a LineString route in visit order
+// @Tags         Travel
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {object} geo.FeatureCollection "Successfully built GeoJSON"
+// @Failure      400 "Invalid UUID format"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/geojson [get]
+func (th *TravelHandlerImpl) GetTravelGeoJSON(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
 
-	err = os.RemoveAll(path)
+	UUID, err := uuid.Parse(uuidStr)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	travel, places, err := th.getTravelPlaces(r.Context(), UUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	tracks, err := th.TrackRepo.GetTracks(r.Context(), UUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	err = json.NewEncoder(w).Encode(geo.TravelFeatureCollection(travel, places, tracks))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 }
 
-func (th *TravelHandlerImpl) GetAllTravels(w http.ResponseWriter, r *http.Request) {
-	travels, err := th.TravelRepo.GetAllTravels(r.Context())
+// ExportGPX godoc
+// @Summary      Export travel to GPX
+// @Description  Export places of the travel as GPX 1.1 waypoints and the route between them as a track
+// @Tags         Travel
+// @Produce      application/gpx+xml
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 "GPX document"
+// @Failure      400 "Invalid UUID format"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/export.gpx [get]
+func (th *TravelHandlerImpl) ExportGPX(w http.ResponseWriter, r *http.Request) {
+	th.export(w, r, "application/gpx+xml", "gpx", geo.WriteGPX)
+}
+
+// ExportKML godoc
+// @Summary      Export travel to KML
+// @Description  Export places of the travel as KML 2.2 placemarks and the route between them as a line
+// @Tags         Travel
+// @Produce      application/vnd.google-earth.kml+xml
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 "KML document"
+// @Failure      400 "Invalid UUID format"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/export.kml [get]
+func (th *TravelHandlerImpl) ExportKML(w http.ResponseWriter, r *http.Request) {
+	th.export(w, r, "application/vnd.google-earth.kml+xml", "kml", geo.WriteKML)
+}
+
+func (th *TravelHandlerImpl) export(w http.ResponseWriter, r *http.Request, contentType, ext string, write func(io.Writer, ds.Travel, []ds.Place) error) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	UUID, err := uuid.Parse(uuidStr)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	for i := range travels {
-		t := &travels[i] // получаем указатель на объект
-		if t.Preview != "" {
-			t.Preview, err = helpers.LoadImage(t.Preview)
+	travel, places, err := th.getTravelPlaces(r.Context(), UUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+	// Собираем документ в буфер, чтобы при ошибке успеть вернуть 500
+	var buf bytes.Buffer
+
+	err = write(&buf, travel, places)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"travel-%s.%s\"", travel.ID, ext))
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorw("failed to write export", "travel", travel.ID, "error", err)
+	}
+}
+
+// ImportGPX godoc
+// @Summary      Import GPX into travel
+// @Description  Parse tracks and waypoints of a GPX file: waypoints become places, tracks are stored for the map. Waypoints without time get the date of the first timed track, the travel start or the import day. Travel dates are widened to cover the imported data. The import is applied atomically
+// @Tags         Travel
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Param        file formData file true "GPX file"
+// @Success      201 {object} ds.TrackImport "Successfully imported GPX"
+// @Failure      400 "Invalid UUID format or GPX file"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/import.gpx [post]
+func (th *TravelHandlerImpl) ImportGPX(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	UUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxGPXSize)
+
+	err = r.ParseMultipartForm(maxGPXSize)
+	if tooLarge(err) {
+		writeError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка при парсинге формы: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Ошибка: не найден файл с ключом 'file'", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	tracks, places, err := geo.ParseGPX(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = requireRole(r.Context(), th.MemberRepo.GetRole, UUID, ds.RoleEditor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	travel, err := th.TravelRepo.GetTravel(r.Context(), UUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result := ds.TrackImport{
+		Places: make([]ds.Place, 0, len(places)),
+		Tracks: make([]ds.Track, 0, len(tracks)),
+	}
+
+	dateStart, dateEnd := travel.DateStart.Time, travel.DateEnd.Time
+	widen := func(t time.Time) {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		if dateStart.IsZero() || day.Before(dateStart) {
+			dateStart = day
+		}
+		if dateEnd.IsZero() || day.After(dateEnd) {
+			dateEnd = day
+		}
+	}
+
+	// Точкам без времени ставим дату начала первого трека со временем,
+	// а если такого нет - дату начала путешествия или день импорта
+	var fallbackDate ds.DateOnlyTime
+
+	for _, track := range tracks {
+		if track.Name == "" {
+			track.Name = travel.Name
+		}
+
+		if track.StartedAt != nil {
+			widen(*track.StartedAt)
+			widen(*track.FinishedAt)
+
+			if fallbackDate.IsZero() {
+				fallbackDate.Time = track.StartedAt.UTC().Truncate(24 * time.Hour)
 			}
 		}
+
+		result.Tracks = append(result.Tracks, track)
+	}
+
+	if fallbackDate.IsZero() {
+		fallbackDate = travel.DateStart
+	}
+	if fallbackDate.IsZero() {
+		fallbackDate.Time = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+
+	for _, place := range places {
+		if place.Date.IsZero() {
+			place.Date = fallbackDate
+		}
+		if place.Name == "" {
+			place.Name = fmt.Sprintf("%.5f, %.5f", *place.Latitude, *place.Longitude)
+		}
+
+		widen(place.Date.Time)
+
+		result.Places = append(result.Places, place)
+	}
+
+	result.DateStart.Time, result.DateEnd.Time = dateStart, dateEnd
+
+	result, err = th.TravelRepo.ImportGPX(r.Context(), UUID, result)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(travels)
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// GetTracks godoc
+// @Summary      Get travel tracks
+// @Description  Retrieve GPX tracks imported into the travel with distance and elevation gain
+// @Tags         Travel
+// @Produce      json
+// @Param        uuid path string true "UUID of the travel"
+// @Success      200 {array} ds.Track "Successfully retrieved tracks"
+// @Failure      400 "Invalid UUID format"
+// @Failure      500 "Internal server error"
+// @Router       /travel/{uuid}/tracks [get]
+func (th *TravelHandlerImpl) GetTracks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uuidStr, ok := vars["uuid"]
+	if !ok {
+		logging.FromContext(r.Context()).Info("uuid is missing in parameters")
+	}
+
+	UUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tracks, err := th.TrackRepo.GetTracks(r.Context(), UUID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(tracks)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// getTravelPlaces возвращает путешествие вместе с его местами (без загрузки изображений)
+func (th *TravelHandlerImpl) getTravelPlaces(ctx context.Context, travelUUID uuid.UUID) (ds.Travel, []ds.Place, error) {
+	travel, err := th.TravelRepo.GetTravel(ctx, travelUUID)
+	if err != nil {
+		return ds.Travel{}, nil, err
+	}
+
+	places := make([]ds.Place, 0, len(travel.Places))
+	for _, placeUUID := range travel.Places {
+		place, err := th.PlaceRepo.GetPlace(ctx, placeUUID)
+		if err != nil {
+			return ds.Travel{}, nil, err
+		}
+		places = append(places, place)
+	}
+
+	return travel, places, nil
+}
+
+// loader собирает FullTravel из репозиториев обработчика
+func (th *TravelHandlerImpl) loader(loadImage func(ctx context.Context, path string) (string, error)) travelLoader {
+	return travelLoader{
+		TravelRepo:   th.TravelRepo,
+		PlaceRepo:    th.PlaceRepo,
+		ExpensesRepo: th.ExpensesRepo,
+		LegRepo:      th.LegRepo,
+		LoadImage:    loadImage,
+	}
+}
+
+// travelLoader собирает путешествие целиком: места с изображениями и расходами, перемещения.
+// LoadImage определяет, как изображения попадают в ответ; пустая строка без ошибки - изображение пропускается
+type travelLoader struct {
+	TravelRepo   repository.TravelRepository
+	PlaceRepo    repository.PlaceRepository
+	ExpensesRepo repository.ExpensesRepository
+	LegRepo      repository.LegRepository
+	LoadImage    func(ctx context.Context, path string) (string, error)
+}
+
+func (tl travelLoader) load(ctx context.Context, travelUUID uuid.UUID) (ds.FullTravel, error) {
+	travel, err := tl.TravelRepo.GetTravel(ctx, travelUUID)
+	if err != nil {
+		return ds.FullTravel{}, err
+	}
+
+	if travel.Preview != "" {
+		travel.Preview, err = tl.LoadImage(ctx, travel.Preview)
+		if err != nil {
+			return ds.FullTravel{}, err
+		}
+	}
+
+	var places []ds.FullPlace
+
+	for _, placeUUID := range travel.Places {
+		place, err := tl.PlaceRepo.GetPlace(ctx, placeUUID)
+		if err != nil {
+			return ds.FullTravel{}, err
+		}
+
+		// LoadImage может пропустить изображение, вернув пустую строку
+		images := place.Images[:0]
+		for _, imagePath := range place.Images {
+			if imagePath == "" {
+				continue
+			}
+			image, err := tl.LoadImage(ctx, imagePath)
+			if err != nil {
+				return ds.FullTravel{}, err
+			}
+			if image != "" {
+				images = append(images, image)
+			}
+		}
+		place.Images = images
+
+		if place.Preview != "" {
+			place.Preview, err = tl.LoadImage(ctx, place.Preview)
+			if err != nil {
+				return ds.FullTravel{}, err
+			}
+		}
+
+		fullPlace := ds.FullPlace{
+			ID:          place.ID,
+			Name:        place.Name,
+			Story:       place.Story,
+			Date:        place.Date,
+			Images:      place.Images,
+			Preview:     place.Preview,
+			Latitude:    place.Latitude,
+			Longitude:   place.Longitude,
+			Address:     place.Address,
+			CountryCode: place.CountryCode,
+		}
+
+		// Записываем expense в Expenses только если place.Expenses не nil
+		if place.Expenses != uuid.Nil {
+			expense, err := tl.ExpensesRepo.GetExpense(ctx, place.Expenses)
+			if err != nil {
+				return ds.FullTravel{}, err
+			}
+			fullPlace.Expenses = &expense
+		}
+
+		places = append(places, fullPlace)
+	}
+
+	legs, err := tl.LegRepo.GetLegs(ctx, travelUUID)
+	if err != nil {
+		return ds.FullTravel{}, err
+	}
+
+	fullLegs, err := fullLegs(ctx, tl.ExpensesRepo, legs)
+	if err != nil {
+		return ds.FullTravel{}, err
+	}
+
+	return ds.FullTravel{
+		ID:          travel.ID,
+		Name:        travel.Name,
+		Description: travel.Description,
+		DateStart:   travel.DateStart,
+		DateEnd:     travel.DateEnd,
+		Places:      places,
+		Legs:        fullLegs,
+		Preview:     travel.Preview,
+	}, nil
 }
@@ -1,32 +1,157 @@
 package helpers
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"lts/internal/app/metrics"
+	"lts/internal/app/tracing"
 )
 
-func LoadImage(path string) (string, error) {
+func LoadImage(ctx context.Context, path string) (string, error) {
+	buffer, err := readImage(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeImage(buffer), nil
+}
+
+// ErrUnsupportedImage - из изображения нельзя надёжно удалить метаданные: это не JPEG или файл повреждён
+var ErrUnsupportedImage = errors.New("image metadata cannot be stripped: not a well-formed JPEG")
+
+// LoadImageWithoutMetadata загружает изображение, удалив из JPEG метаданные EXIF/XMP (в том числе координаты съёмки).
+// Если метаданные удалить нельзя, возвращает ErrUnsupportedImage, а не исходный файл
+func LoadImageWithoutMetadata(ctx context.Context, path string) (string, error) {
+	buffer, err := readImage(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	buffer, err = StripMetadata(buffer)
+	metrics.Since(metrics.ImageDuration.WithLabelValues("strip_metadata"), start)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeImage(buffer), nil
+}
+
+// StripMetadata удаляет из JPEG сегменты APP1-APP15 (EXIF, XMP, IPTC и др.) и комментарии,
+// а также всё после маркера конца изображения, где хранятся дополнительные кадры со своими EXIF.
+// Для данных, которые не удаётся разобрать как JPEG, возвращает ErrUnsupportedImage
+func StripMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrUnsupportedImage
+	}
+
+	result := make([]byte, 0, len(data))
+	result = append(result, data[:2]...)
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil, ErrUnsupportedImage
+		}
+
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Заполняющий байт
+			i++
+			continue
+		}
+
+		// Начало сжатых данных: дальше заголовков с метаданными нет, копируем до конца изображения.
+		// В сжатых данных 0xFF всегда экранируется, поэтому первый FF D9 - конец изображения
+		if marker == 0xDA {
+			eoi := bytes.Index(data[i:], []byte{0xFF, 0xD9})
+			if eoi < 0 {
+				return nil, ErrUnsupportedImage
+			}
+			return append(result, data[i:i+eoi+2]...), nil
+		}
+
+		length := int(data[i+2])<<8 | int(data[i+3])
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrUnsupportedImage
+		}
+
+		if (marker < 0xE1 || marker > 0xEF) && marker != 0xFE {
+			result = append(result, data[i:end]...)
+		}
+		i = end
+	}
+
+	return nil, ErrUnsupportedImage
+}
+
+// SaveImage записывает содержимое src в файл path, создавая или перезаписывая его.
+// Возвращает размер записанного файла
+func SaveImage(ctx context.Context, path string, src io.Reader) (written int64, err error) {
+	_, span := tracing.Start(ctx, "storage.write", trace.WithAttributes(attribute.String("storage.path", path)))
+	defer func() { tracing.End(span, err) }()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("[os.Create]: %w", err)
+	}
+	defer func() {
+		closeErr := file.Close()
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("[file.Close]: %w", closeErr)
+		}
+	}()
+
+	written, err = io.Copy(file, src)
+	metrics.StorageBytes.WithLabelValues("write").Add(float64(written))
+	span.SetAttributes(attribute.Int64("storage.bytes", written))
+	if err != nil {
+		return written, fmt.Errorf("[io.Copy]: %w", err)
+	}
+
+	return written, nil
+}
+
+func readImage(ctx context.Context, path string) (_ []byte, err error) {
+	_, span := tracing.Start(ctx, "storage.read", trace.WithAttributes(attribute.String("storage.path", path)))
+	defer func() { tracing.End(span, err) }()
+
 	file, err := os.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("ошибка при открытии файла: %w", err)
+		return nil, fmt.Errorf("ошибка при открытии файла: %w", err)
 	}
 	defer file.Close()
 
 	fileInfo, err := file.Stat()
 	if err != nil {
-		return "", fmt.Errorf("ошибка при получении информации о файле: %w", err)
+		return nil, fmt.Errorf("ошибка при получении информации о файле: %w", err)
 	}
 
 	size := fileInfo.Size()
 	buffer := make([]byte, size)
 
-	_, err = file.Read(buffer)
+	n, err := file.Read(buffer)
+	metrics.StorageBytes.WithLabelValues("read").Add(float64(n))
+	span.SetAttributes(attribute.Int64("storage.bytes", int64(n)))
 	if err != nil {
-		return "", fmt.Errorf("ошибка при чтении файла: %w", err)
+		return nil, fmt.Errorf("ошибка при чтении файла: %w", err)
 	}
 
-	base64Encoding := "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buffer)
+	return buffer, nil
+}
+
+func encodeImage(buffer []byte) string {
+	defer metrics.Since(metrics.ImageDuration.WithLabelValues("encode"), time.Now())
 
-	return base64Encoding, nil
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buffer)
 }
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"go.uber.org/zap"
+
+	"lts/internal/app/config"
+)
+
+// New создаёт логгер приложения по секции log конфигурации. Уровень можно менять на ходу через
+// возвращаемый zap.AtomicLevel, формат и sampling применяются только при запуске
+func New(cfg config.Log) (*zap.SugaredLogger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, level, err
+	}
+
+	zapCfg := zap.NewProductionConfig()
+	zapCfg.Level = level
+	zapCfg.Sampling = nil
+	if cfg.Sampling.Initial > 0 {
+		zapCfg.Sampling = &zap.SamplingConfig{Initial: cfg.Sampling.Initial, Thereafter: cfg.Sampling.Thereafter}
+	}
+	if cfg.Format == "console" {
+		zapCfg.Encoding = "console"
+		zapCfg.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+
+	logger, err := zapCfg.Build()
+	if err != nil {
+		return nil, level, err
+	}
+	return logger.Sugar(), level, nil
+}
+
+// Bootstrap - логгер для сообщений до чтения конфигурации, в формате по умолчанию
+func Bootstrap() *zap.SugaredLogger {
+	logger, _, err := New(config.Log{Level: "info", Format: "json"})
+	if err != nil {
+		return zap.NewNop().Sugar()
+	}
+	return logger
+}
@@ -0,0 +1,23 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type key struct{}
+
+// With кладёт в контекст логгер запроса
+func With(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, key{}, logger)
+}
+
+// FromContext достаёт логгер запроса с его id, trace_id и пользователем.
+// Вне запроса возвращает общий логгер приложения, см. zap.ReplaceGlobals
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(key{}).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return zap.S()
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"lts/internal/app/repository"
+)
+
+// AuthMiddleware пропускает только запросы с действующим access-токеном или API-ключом
+// и кладёт пользователя в контекст для обработчиков и репозиториев.
+// Access-токен проверяется по подписи, без обращения к БД.
+// API-ключ передаётся в заголовке X-API-Key или как Bearer-токен с префиксом auth.APIKeyPrefix
+func AuthMiddleware(tokens *auth.TokenManager, keys repository.APIKeyRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-API-Key")
+			if token == "" {
+				var err error
+				token, err = auth.BearerToken(r)
+				if err != nil {
+					unauthorized(w, err.Error())
+					return
+				}
+			}
+
+			var principal auth.Principal
+			var err error
+
+			if auth.IsAPIKey(token) {
+				principal, err = keys.Authenticate(r.Context(), auth.HashToken(token))
+				if errors.Is(err, repository.ErrNotFound) {
+					unauthorized(w, "invalid, expired or revoked api key")
+					return
+				}
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			} else {
+				principal, err = tokens.Parse(token)
+				if err != nil {
+					unauthorized(w, auth.ErrInvalidToken.Error())
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// MethodScopes требует от API-ключа право read для безопасных методов и write для остальных
+func MethodScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := ds.ScopeWrite
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = ds.ScopeRead
+		}
+
+		RequireScope(scope)(next).ServeHTTP(w, r)
+	})
+}
+
+// RequireScope требует от API-ключа право scope. Для входа по access-токену проверка всегда проходит
+func RequireScope(scope ds.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				unauthorized(w, auth.ErrNoToken.Error())
+				return
+			}
+
+			if !principal.Allows(scope) {
+				http.Error(w, "api key has no "+string(scope)+" scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="lts"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"lts/internal/app/auth"
+	"lts/internal/app/config"
+	"lts/internal/app/ds"
+	"lts/internal/app/repository"
+)
+
+// fakeKeys - API-ключи в памяти: ключ -> принципал
+type fakeKeys struct {
+	repository.APIKeyRepository
+	keys map[string]auth.Principal
+	err  error
+}
+
+func (f fakeKeys) Authenticate(_ context.Context, keyHash string) (auth.Principal, error) {
+	if f.err != nil {
+		return auth.Principal{}, f.err
+	}
+	for key, principal := range f.keys {
+		if auth.HashToken(key) == keyHash {
+			return principal, nil
+		}
+	}
+	return auth.Principal{}, repository.ErrNotFound
+}
+
+func TestAuthScopes(t *testing.T) {
+	tokens, err := auth.NewTokenManager(config.AuthConfig{
+		Issuer:    "lts",
+		AccessTTL: time.Minute,
+		ActiveKey: "test",
+		Keys:      []config.SigningKey{{ID: "test", Secret: "0123456789abcdef0123456789abcdef"}},
+	})
+	if err != nil {
+		t.Fatalf("NewTokenManager() error = %v", err)
+	}
+
+	userID := uuid.New()
+	access, _, err := tokens.Issue(auth.Principal{UserID: userID, Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	const (
+		readKey  = auth.APIKeyPrefix + "read"
+		writeKey = auth.APIKeyPrefix + "write"
+		mediaKey = auth.APIKeyPrefix + "media"
+	)
+	keys := fakeKeys{keys: map[string]auth.Principal{
+		readKey:  {UserID: userID, APIKeyID: uuid.New(), Scopes: []ds.Scope{ds.ScopeRead}},
+		writeKey: {UserID: userID, APIKeyID: uuid.New(), Scopes: []ds.Scope{ds.ScopeWrite}},
+		mediaKey: {UserID: userID, APIKeyID: uuid.New(), Scopes: []ds.Scope{ds.ScopeRead, ds.ScopeMedia}},
+	}}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, found := auth.FromContext(r.Context())
+		if !found || principal.UserID != userID {
+			t.Errorf("principal = %+v, want user %s", principal, userID)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	api := AuthMiddleware(tokens, keys)(MethodScopes(ok))
+	media := AuthMiddleware(tokens, keys)(RequireScope(ds.ScopeMedia)(ok))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		method  string
+		header  string
+		value   string
+		status  int
+	}{
+		{"no credentials", api, http.MethodGet, "", "", http.StatusUnauthorized},
+		{"invalid access token", api, http.MethodGet, "Authorization", "Bearer not-a-jwt", http.StatusUnauthorized},
+		{"access token reads", api, http.MethodGet, "Authorization", "Bearer " + access, http.StatusOK},
+		{"access token writes", api, http.MethodDelete, "Authorization", "Bearer " + access, http.StatusOK},
+		{"access token uploads", media, http.MethodPost, "Authorization", "Bearer " + access, http.StatusOK},
+		{"unknown api key", api, http.MethodGet, "X-API-Key", auth.APIKeyPrefix + "unknown", http.StatusUnauthorized},
+		{"read key reads", api, http.MethodGet, "X-API-Key", readKey, http.StatusOK},
+		{"read key as bearer", api, http.MethodGet, "Authorization", "Bearer " + readKey, http.StatusOK},
+		{"read key cannot write", api, http.MethodPost, "X-API-Key", readKey, http.StatusForbidden},
+		{"read key cannot upload", media, http.MethodPost, "X-API-Key", readKey, http.StatusForbidden},
+		{"write key writes", api, http.MethodPut, "X-API-Key", writeKey, http.StatusOK},
+		{"write key cannot read", api, http.MethodGet, "X-API-Key", writeKey, http.StatusForbidden},
+		{"media key uploads", media, http.MethodPost, "X-API-Key", mediaKey, http.StatusOK},
+		{"media key cannot write", api, http.MethodPatch, "X-API-Key", mediaKey, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/travel", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestAuthKeyStoreError(t *testing.T) {
+	tokens, err := auth.NewTokenManager(config.AuthConfig{
+		Issuer:    "lts",
+		AccessTTL: time.Minute,
+		ActiveKey: "test",
+		Keys:      []config.SigningKey{{ID: "test", Secret: "0123456789abcdef0123456789abcdef"}},
+	})
+	if err != nil {
+		t.Fatalf("NewTokenManager() error = %v", err)
+	}
+
+	handler := AuthMiddleware(tokens, fakeKeys{err: errors.New("connection refused")})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach the handler")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/travel", nil)
+	r.Header.Set("X-API-Key", auth.APIKeyPrefix+"any")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"lts/internal/app/config"
+)
+
+func TestAllowedOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		origin   string
+		want     bool
+	}{
+		{"no patterns", nil, "https://example.com", false},
+		{"any", []string{"*"}, "https://example.com", true},
+		{"exact", []string{"https://example.com"}, "https://example.com", true},
+		{"exact other scheme", []string{"https://example.com"}, "http://example.com", false},
+		{"exact other port", []string{"https://example.com"}, "https://example.com:8443", false},
+		{"second pattern", []string{"https://a.com", "https://b.com"}, "https://b.com", true},
+		{"subdomain", []string{"https://*.example.com"}, "https://app.example.com", true},
+		{"nested subdomain", []string{"https://*.example.com"}, "https://a.b.example.com", true},
+		{"apex is not a subdomain", []string{"https://*.example.com"}, "https://example.com", false},
+		{"empty subdomain", []string{"https://*.example.com"}, "https://.example.com", false},
+		{"suffix lookalike", []string{"https://*.example.com"}, "https://evilexample.com", false},
+		{"other scheme", []string{"https://*.example.com"}, "http://app.example.com", false},
+		{"port is not a subdomain", []string{"https://*.example.com"}, "https://app.example.com:8443", false},
+		{"subdomain with port", []string{"https://*.example.com:8443"}, "https://app.example.com:8443", true},
+		{"userinfo", []string{"https://*.example.com"}, "https://user@evil.example.com", false},
+		{"path", []string{"https://*.example.com"}, "https://evil.com/.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedOrigin(tt.patterns, tt.origin); got != tt.want {
+				t.Fatalf("allowedOrigin(%v, %q) = %v, want %v", tt.patterns, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	policy := config.CORS{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		request string
+		status  int
+		headers map[string]string
+	}{
+		{
+			name:   "allowed request",
+			method: http.MethodGet,
+			origin: "https://app.example.com",
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-ID",
+				"Access-Control-Allow-Methods":     "",
+			},
+		},
+		{
+			name:    "allowed preflight",
+			method:  http.MethodOptions,
+			origin:  "https://app.example.com",
+			request: "POST",
+			status:  http.StatusNoContent,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":   "https://app.example.com",
+				"Access-Control-Allow-Methods":  "GET, POST",
+				"Access-Control-Allow-Headers":  "Authorization",
+				"Access-Control-Max-Age":        "600",
+				"Access-Control-Expose-Headers": "",
+			},
+		},
+		{
+			name:   "foreign origin",
+			method: http.MethodGet,
+			origin: "https://evil.com",
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name:    "foreign preflight",
+			method:  http.MethodOptions,
+			origin:  "https://evil.com",
+			request: "POST",
+			status:  http.StatusNoContent,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name:   "same origin",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+	}
+
+	handler := CORS(func() config.CORS { return policy })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/travel", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.request != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.request)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if vary := w.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Origin" {
+				t.Fatalf("Vary = %v, want Origin first", vary)
+			}
+			for header, want := range tt.headers {
+				if got := w.Header().Get(header); got != want {
+					t.Fatalf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,20 +1,91 @@
 package middleware
 
 import (
-	"go.uber.org/zap"
+	"context"
 	"net/http"
 	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"lts/internal/app/auth"
+	"lts/internal/app/logging"
+	"lts/internal/app/requestid"
+	"lts/internal/app/tracing"
 )
 
-func LogMiddleware(logger *zap.SugaredLogger, next http.Handler) http.Handler {
+// AccessLog кладёт в контекст логгер запроса с request_id и после ответа пишет строку журнала доступа:
+// маршрут, код и размер ответа, пользователь, длительность. Оборачивает весь роутер внутри RequestID,
+// чтобы в журнал попадали и запросы без маршрута (404, 405)
+func AccessLog(logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestLogger := logger.With("request_id", requestid.FromContext(r.Context()))
+
+			entry := &accessEntry{route: "unknown"}
+			ctx := context.WithValue(logging.With(r.Context(), requestLogger), accessEntryKey{}, entry)
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			write := requestLogger.Infow
+			if sw.status >= http.StatusInternalServerError {
+				write = requestLogger.Errorw
+			}
+			write("request", append(entry.trace,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", entry.route,
+				"status", sw.status,
+				"size", sw.size,
+				"user", entry.user,
+				"remote_addr", r.RemoteAddr,
+				"duration", time.Since(start),
+			)...)
+		})
+	}
+}
+
+// AccessRoute дописывает в логгер запроса trace_id и span_id, а в строку журнала доступа - ещё и шаблон маршрута.
+// Ставится через Router.Use после otelmux: вне роутера маршрут и span ещё неизвестны
+func AccessRoute(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		logger.Infow("New request",
-			"method", r.Method,
-			"remote_addr", r.RemoteAddr,
-			"url", r.URL.Path,
-			"time", time.Since(start),
-		)
+		ctx := r.Context()
+
+		fields := tracing.LogFields(ctx)
+		if entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+			entry.route = routeTemplate(r)
+			entry.trace = fields
+		}
+		if len(fields) > 0 {
+			ctx = logging.With(ctx, logging.FromContext(ctx).With(fields...))
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// accessEntry - то, что становится известно о запросе глубже по цепочке middleware
+type accessEntry struct {
+	route string
+	trace []any
+	user  string
+}
+
+type accessEntryKey struct{}
+
+// withPrincipal дописывает пользователя в логгер запроса и в строку журнала доступа
+func withPrincipal(ctx context.Context, principal auth.Principal) context.Context {
+	user := principal.UserID.String()
+	if entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.user = user
+	}
+
+	logger := logging.FromContext(ctx).With("user", user)
+	if principal.APIKeyID != uuid.Nil {
+		logger = logger.With("api_key", principal.APIKeyID.String())
+	}
+	return logging.With(auth.WithPrincipal(ctx, principal), logger)
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"lts/internal/app/requestid"
+)
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	r := mux.NewRouter()
+	r.Use(AccessRoute)
+	r.HandleFunc("/travel/{uuid}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	handler := RequestID(AccessLog(zap.New(core).Sugar())(r))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		route  string
+	}{
+		{"matched route", "GET", "/travel/1", http.StatusOK, "/travel/{uuid}"},
+		{"not found", "GET", "/missing", http.StatusNotFound, "unknown"},
+		{"method not allowed", "POST", "/travel/1", http.StatusMethodNotAllowed, "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			id := rec.Header().Get(requestid.Header)
+			if id == "" {
+				t.Fatalf("response has no %s header", requestid.Header)
+			}
+
+			entries := logs.TakeAll()
+			if len(entries) != 1 {
+				t.Fatalf("got %d log entries, want 1", len(entries))
+			}
+
+			fields := entries[0].ContextMap()
+			if fields["request_id"] != id {
+				t.Fatalf("request_id = %v, want %s", fields["request_id"], id)
+			}
+			if fields["status"] != int64(tt.status) {
+				t.Fatalf("status = %v, want %d", fields["status"], tt.status)
+			}
+			if fields["route"] != tt.route {
+				t.Fatalf("route = %v, want %s", fields["route"], tt.route)
+			}
+		})
+	}
+}
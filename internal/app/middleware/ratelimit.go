@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"lts/internal/app/auth"
+	"lts/internal/app/logging"
+	"lts/internal/app/metrics"
+	"lts/internal/app/ratelimit"
+)
+
+// RateLimit ограничивает частоту запросов по ключу, который возвращает key.
+// Пустой ключ - запрос не ограничивается. При недоступности хранилища лимитов запрос пропускается,
+// ошибка пишется в лог запроса и в метрику lts_rate_limit_errors_total.
+// Лимит запрашивается у limit на каждый запрос, чтобы изменения конфигурации применялись сразу
+func RateLimit(limiter ratelimit.Limiter, limit func() ratelimit.Limit, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := limiter.Allow(r.Context(), k, limit())
+			if err != nil {
+				metrics.RateLimitErrors.Inc()
+				logging.FromContext(r.Context()).Errorw("rate limiter is unavailable", "key", k, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP - ключ лимита по адресу клиента
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// PrincipalKey - ключ лимита по пользователю. Ставится после AuthMiddleware
+func PrincipalKey(r *http.Request) string {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	return "user:" + principal.UserID.String()
+}
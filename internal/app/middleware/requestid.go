@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"lts/internal/app/requestid"
+
+	"github.com/google/uuid"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID берёт id запроса из заголовка X-Request-ID или выдаёт новый,
+// кладёт его в контекст и возвращает клиенту в том же заголовке
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.With(r.Context(), id)))
+	})
+}
+
+// validRequestID пропускает только непустые id из печатных ASCII-символов разумной длины
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"lts/internal/app/config"
+	"lts/internal/app/ds"
+)
+
+var (
+	ErrTooLarge = errors.New("upload is too large")
+	ErrExceeded = errors.New("storage quota exceeded")
+)
+
+// Quota проверяет загрузки до записи на диск. Файлы путешествия лежат в Root/<uuid путешествия>.
+// Лимиты можно менять на ходу через SetLimits
+type Quota struct {
+	Root   string
+	limits atomic.Pointer[config.Quota]
+}
+
+func New(root string, cfg config.Quota) *Quota {
+	q := &Quota{Root: root}
+	q.SetLimits(cfg)
+	return q
+}
+
+// Limits - текущие лимиты
+func (q *Quota) Limits() config.Quota {
+	return *q.limits.Load()
+}
+
+func (q *Quota) SetLimits(cfg config.Quota) {
+	q.limits.Store(&cfg)
+}
+
+// File описывает записанный файл path путешествия travel и места place для учёта в квоте
+func (q *Quota) File(path string, travel, place uuid.UUID, size int64) (ds.StoredFile, error) {
+	rel, err := filepath.Rel(q.Root, path)
+	if err != nil {
+		return ds.StoredFile{}, fmt.Errorf("[filepath.Rel]: %w", err)
+	}
+	return ds.StoredFile{Path: filepath.ToSlash(rel), TravelID: travel, PlaceID: place, Size: size}, nil
+}
+
+// Files - все файлы хранилища. Нужен один раз, чтобы учесть файлы, записанные до появления учёта в БД.
+// Путешествие и место определяются по пути: Root/<путешествие>/places/<место>/...
+func (q *Quota) Files() ([]ds.StoredFile, error) {
+	var files []ds.StoredFile
+
+	err := filepath.WalkDir(q.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(q.Root, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+
+		travel, err := uuid.Parse(parts[0])
+		if err != nil || len(parts) < 2 {
+			return nil
+		}
+
+		var place uuid.UUID
+		if len(parts) > 3 && parts[1] == "places" {
+			place, _ = uuid.Parse(parts[2])
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, ds.StoredFile{Path: filepath.ToSlash(rel), TravelID: travel, PlaceID: place, Size: info.Size()})
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("[filepath.WalkDir]: %w", err)
+	}
+
+	return files, nil
+}
+
+// Check проверяет, что загрузка размером size укладывается в лимит запроса
+// и вместе с уже занятым местом used - в квоту пользователя
+func (q *Quota) Check(used, size int64) error {
+	limits := q.Limits()
+
+	if limits.MaxUpload > 0 && size > limits.MaxUpload {
+		return ErrTooLarge
+	}
+	if limits.UserStorage > 0 && used+size > limits.UserStorage {
+		return ErrExceeded
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package quota
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"lts/internal/app/config"
+	"lts/internal/app/ds"
+)
+
+func TestFiles(t *testing.T) {
+	root := t.TempDir()
+	travel := uuid.New()
+	place := uuid.New()
+
+	write := func(path string, size int) {
+		t.Helper()
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(travel.String()+"/preview.jpg", 3)
+	write(travel.String()+"/places/"+place.String()+"/preview.jpg", 5)
+	write(travel.String()+"/places/"+place.String()+"/images/a.png", 7)
+	write("not-a-travel/preview.jpg", 11)
+
+	q := New(root, config.Quota{})
+
+	got, err := q.Files()
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+
+	want := []ds.StoredFile{
+		{Path: travel.String() + "/places/" + place.String() + "/images/a.png", TravelID: travel, PlaceID: place, Size: 7},
+		{Path: travel.String() + "/places/" + place.String() + "/preview.jpg", TravelID: travel, PlaceID: place, Size: 5},
+		{Path: travel.String() + "/preview.jpg", TravelID: travel, Size: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Files() = %v, want %v", got, want)
+	}
+
+	file, err := q.File(filepath.Join(root, travel.String(), "preview.jpg"), travel, uuid.Nil, 3)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if file != want[2] {
+		t.Fatalf("File() = %v, want %v", file, want[2])
+	}
+}
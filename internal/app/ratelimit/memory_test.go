@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 5}
+
+	tests := []struct {
+		name   string
+		tokens float64
+		left   float64
+		want   Result
+	}{
+		{"full", 5, 4, Result{Allowed: true, Limit: 5, Remaining: 4}},
+		{"last token", 1, 0, Result{Allowed: true, Limit: 5, Remaining: 0}},
+		{"partial token left", 1.5, 0.5, Result{Allowed: true, Limit: 5, Remaining: 0}},
+		{"empty", 0, 0, Result{Limit: 5, RetryAfter: 500 * time.Millisecond}},
+		{"almost a token", 0.75, 0.75, Result{Limit: 5, RetryAfter: 125 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, got := take(tt.tokens, limit)
+			if left != tt.left || got != tt.want {
+				t.Fatalf("take(%v) = %v, %+v, want %v, %+v", tt.tokens, left, got, tt.left, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryAllow(t *testing.T) {
+	limit := Limit{Rate: 1, Burst: 3}
+
+	type step struct {
+		after     time.Duration // сколько прошло с предыдущего запроса
+		key       string
+		allowed   bool
+		remaining int
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst then reject",
+			steps: []step{
+				{0, "a", true, 2},
+				{0, "a", true, 1},
+				{0, "a", true, 0},
+				{0, "a", false, 0},
+			},
+		},
+		{
+			name: "refill by rate",
+			steps: []step{
+				{0, "a", true, 2},
+				{0, "a", true, 1},
+				{0, "a", true, 0},
+				{time.Second, "a", true, 0},
+				{500 * time.Millisecond, "a", false, 0},
+				{500 * time.Millisecond, "a", true, 0},
+			},
+		},
+		{
+			name: "refill is capped by burst",
+			steps: []step{
+				{0, "a", true, 2},
+				{time.Hour, "a", true, 2},
+			},
+		},
+		{
+			name: "keys are independent",
+			steps: []step{
+				{0, "a", true, 2},
+				{0, "a", true, 1},
+				{0, "a", true, 0},
+				{0, "a", false, 0},
+				{0, "b", true, 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+			m := NewMemory()
+			m.lastSweep = now
+			m.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = now.Add(s.after)
+
+				result, err := m.Allow(context.Background(), s.key, limit)
+				if err != nil {
+					t.Fatalf("step %d: Allow() error = %v", i, err)
+				}
+				if result.Allowed != s.allowed || result.Remaining != s.remaining || result.Limit != limit.Burst {
+					t.Fatalf("step %d: Allow() = %+v, want allowed %v, remaining %d", i, result, s.allowed, s.remaining)
+				}
+				if !result.Allowed && result.RetryAfter <= 0 {
+					t.Fatalf("step %d: rejected request must have RetryAfter", i)
+				}
+			}
+		})
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	now := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.lastSweep = now
+	m.now = func() time.Time { return now }
+
+	// idle заполняется за 10 секунд, busy - за 100
+	_, _ = m.Allow(context.Background(), "idle", Limit{Rate: 1, Burst: 10})
+	_, _ = m.Allow(context.Background(), "busy", Limit{Rate: 1, Burst: 100})
+
+	// До sweepInterval корзины не проверяются
+	now = now.Add(sweepInterval / 2)
+	_, _ = m.Allow(context.Background(), "other", Limit{Rate: 1, Burst: 10})
+	if len(m.buckets) != 3 {
+		t.Fatalf("buckets before sweep interval = %d, want 3", len(m.buckets))
+	}
+
+	now = now.Add(sweepInterval / 2)
+	_, _ = m.Allow(context.Background(), "other", Limit{Rate: 1, Burst: 10})
+
+	_, idle := m.buckets["idle"]
+	_, busy := m.buckets["busy"]
+	if idle || !busy {
+		t.Fatalf("after sweep idle = %v, busy = %v, want false, true", idle, busy)
+	}
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit - параметры token bucket: корзина вмещает Burst токенов и пополняется
+// со скоростью Rate токенов в секунду, каждый запрос забирает один токен
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result - решение по одному запросу
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter хранит корзины по ключам (ip, пользователь)
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take списывает токен из корзины, в которой осталось tokens, и формирует результат
+func take(tokens float64, limit Limit) (float64, Result) {
+	result := Result{Limit: limit.Burst}
+
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	return tokens, result
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucket пополняет и списывает корзину атомарно на стороне Redis.
+// Время берётся из Redis, чтобы расхождение часов экземпляров сервиса не влияло на лимит
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(data[1]) or burst
+local updated = tonumber(data[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil(burst / rate) + 1)
+
+return {allowed, tostring(tokens)}
+`)
+
+// Redis - token bucket в Redis, общий для всех экземпляров сервиса
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client, prefix: "lts:ratelimit:"}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := tokenBucket.Run(ctx, r.client, []string{r.prefix + key}, limit.Rate, limit.Burst).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("[tokenBucket.Run]: %w", err)
+	}
+
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply from redis: %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	left, _ := reply[1].(string)
+
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("[strconv.ParseFloat]: %w", err)
+	}
+
+	// Скрипт уже списал токен, take здесь нужен только для расчёта заголовков
+	if allowed == 1 {
+		tokens++
+	}
+	_, result := take(tokens, limit)
+	return result, nil
+}
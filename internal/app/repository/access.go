@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"strings"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrConflict        = errors.New("already exists")
+	ErrForbidden       = errors.New("insufficient role")
+)
+
+// currentUser - пользователь, от имени которого выполняется запрос.
+// При просмотре по публичной ссылке возвращается id ссылки: travelScope с ролью viewer
+// пропускает его к путешествию ссылки, а остальные проверки - нет
+func currentUser(ctx context.Context) (uuid.UUID, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return uuid.Nil, ErrUnauthenticated
+	}
+	if principal.ShareID != uuid.Nil {
+		return principal.ShareID, nil
+	}
+	return principal.UserID, nil
+}
+
+// Подзапросы ниже ограничивают выборку сущностями, к которым у пользователя
+// (id передан параметром $arg) есть доступ с ролью не ниже role.
+// Все проверки доступа в репозиториях идут через них
+
+// travelScope - путешествия не из корзины, в которых пользователь участвует с ролью не ниже role
+func travelScope(arg int, role ds.Role) string {
+	return fmt.Sprintf("SELECT id FROM travel WHERE deleted_at IS NULL AND id IN (%s)", memberScope(arg, role))
+}
+
+// memberScope - путешествия, включая удалённые в корзину, в которых пользователь участвует с ролью не ниже role
+func memberScope(arg int, role ds.Role) string {
+	scope := fmt.Sprintf("SELECT travel_id FROM travel_members WHERE user_id = $%d AND role IN (%s)", arg, rolesFrom(role))
+	if role == ds.RoleViewer {
+		scope += fmt.Sprintf(" UNION SELECT travel_id FROM travel_shares WHERE id = $%d AND %s", arg, shareActive)
+	}
+	return scope
+}
+
+// shareActive - условие действующей публичной ссылки
+const shareActive = "revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())"
+
+// placeScope - места не из корзины в таких путешествиях
+func placeScope(arg int, role ds.Role) string {
+	return fmt.Sprintf("SELECT p.id FROM places p JOIN travel t ON p.id = ANY(t.places) WHERE p.deleted_at IS NULL AND t.id IN (%s)", travelScope(arg, role))
+}
+
+// expenseScope - расходы мест и перемещений в таких путешествиях
+func expenseScope(arg int, role ds.Role) string {
+	return fmt.Sprintf("SELECT expenses FROM places WHERE id IN (%s) UNION SELECT expenses FROM legs WHERE travel_id IN (%s) AND %s", placeScope(arg, role), travelScope(arg, role), legLive)
+}
+
+// legLive - условие перемещения, оба места которого не в корзине.
+// Раньше такие перемещения удалялись вместе с местом, теперь они скрываются до его восстановления
+const legLive = "NOT EXISTS (SELECT 1 FROM places lp WHERE lp.id IN (legs.from_place, legs.to_place) AND lp.deleted_at IS NOT NULL)"
+
+// rolesFrom перечисляет для IN (...) роли, включающие права role
+func rolesFrom(role ds.Role) string {
+	var roles []string
+	for _, r := range ds.Roles {
+		if r.Allows(role) {
+			roles = append(roles, "'"+string(r)+"'")
+		}
+	}
+	return strings.Join(roles, ", ")
+}
+
+// notFound превращает sql.ErrNoRows в ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// affected возвращает ErrNotFound, если запрос не затронул ни одной строки
+func affected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("[result.RowsAffected]: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkTravel возвращает ErrNotFound, если пользователь не участвует в путешествии,
+// и ErrForbidden, если его роль ниже role
+func checkTravel(ctx context.Context, db *sqlx.DB, travelUUID uuid.UUID, role ds.Role) error {
+	current, err := travelRole(ctx, db, travelUUID)
+	if err != nil {
+		return err
+	}
+	if !current.Allows(role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// travelRole - роль текущего пользователя в путешествии
+func travelRole(ctx context.Context, db *sqlx.DB, travelUUID uuid.UUID) (ds.Role, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var role ds.Role
+	err = db.GetContext(ctx, &role, "SELECT m.role FROM travel_members m JOIN travel t ON t.id = m.travel_id WHERE m.travel_id = $1 AND m.user_id = $2 AND t.deleted_at IS NULL", travelUUID, userID)
+	if err != nil {
+		return "", fmt.Errorf("[db.GetContext]: %w", notFound(err))
+	}
+	return role, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+)
+
+// apiKeyActive - условие действующего API-ключа
+const apiKeyActive = "revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())"
+
+type APIKeyRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyRepo(db *sqlx.DB) *APIKeyRepositoryImpl {
+	return &APIKeyRepositoryImpl{
+		db: db,
+	}
+}
+
+func (a APIKeyRepositoryImpl) CreateAPIKey(ctx context.Context, key ds.APIKey, keyHash string) (ds.APIKey, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.APIKey{}, err
+	}
+
+	key.ID = uuid.New()
+
+	err = a.db.QueryRowContext(ctx, "INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at",
+		key.ID, userID, key.Name, key.Prefix, keyHash, scopeArray(key.Scopes), key.ExpiresAt).Scan(&key.CreatedAt)
+	if err != nil {
+		return ds.APIKey{}, fmt.Errorf("[db.QueryRowContext]: %w", err)
+	}
+	return key, nil
+}
+
+// GetAPIKeys - неотозванные ключи текущего пользователя, в том числе истёкшие
+func (a APIKeyRepositoryImpl) GetAPIKeys(ctx context.Context) ([]ds.APIKey, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ds.APIKey{}
+
+	rows, err := a.db.QueryContext(ctx, "SELECT id, name, prefix, scopes, created_at, expires_at, last_used_at FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key ds.APIKey
+		var scopes pq.StringArray
+		var expiresAt, lastUsedAt sql.NullTime
+
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+
+		key.Scopes = toScopes(scopes)
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			key.LastUsedAt = &lastUsedAt.Time
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (a APIKeyRepositoryImpl) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := a.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return affected(result)
+}
+
+// Authenticate находит действующий ключ по хешу и отмечает его использование.
+// Время использования обновляется не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+func (a APIKeyRepositoryImpl) Authenticate(ctx context.Context, keyHash string) (auth.Principal, error) {
+	var principal auth.Principal
+	var scopes pq.StringArray
+
+	err := a.db.QueryRowContext(ctx, "SELECT k.id, k.user_id, u.email, k.scopes FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.key_hash = $1 AND k."+apiKeyActive, keyHash).Scan(
+		&principal.APIKeyID, &principal.UserID, &principal.Email, &scopes,
+	)
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+
+	principal.Scopes = toScopes(scopes)
+
+	_, err = a.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = now() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')", principal.APIKeyID)
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+
+	return principal, nil
+}
+
+func scopeArray(scopes []ds.Scope) pq.StringArray {
+	result := make(pq.StringArray, len(scopes))
+	for i, scope := range scopes {
+		result[i] = string(scope)
+	}
+	return result
+}
+
+func toScopes(scopes pq.StringArray) []ds.Scope {
+	result := make([]ds.Scope, len(scopes))
+	for i, scope := range scopes {
+		result[i] = ds.Scope(scope)
+	}
+	return result
+}
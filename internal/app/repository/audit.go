@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"lts/internal/app/requestid"
+	"time"
+)
+
+type AuditRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepo(db *sqlx.DB) *AuditRepositoryImpl {
+	return &AuditRepositoryImpl{
+		db: db,
+	}
+}
+
+// GetHistory - журнал изменений путешествия от новых записей к старым.
+// before - id записи, с которой продолжается предыдущая страница, 0 - с начала
+func (a AuditRepositoryImpl) GetHistory(ctx context.Context, travelUUID uuid.UUID, before int64, limit int) ([]ds.AuditEntry, error) {
+	err := checkTravel(ctx, a.db, travelUUID, ds.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ds.AuditEntry{}
+
+	rows, err := a.db.QueryContext(ctx, `SELECT a.id, a.travel_id, a.actor_id, u.email, a.api_key_id, a.action, a.entity_type, a.entity_id, a.before, a.after, a.request_id, a.created_at
+		FROM audit_log a LEFT JOIN users u ON u.id = a.actor_id
+		WHERE a.travel_id = $1 AND ($2::bigint = 0 OR a.id < $2) ORDER BY a.id DESC LIMIT $3`, travelUUID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry ds.AuditEntry
+		var actorID, apiKeyID uuid.NullUUID
+		var email, requestID sql.NullString
+		var before, after []byte
+
+		err := rows.Scan(&entry.ID, &entry.TravelID, &actorID, &email, &apiKeyID, &entry.Action, &entry.EntityType, &entry.EntityID, &before, &after, &requestID, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+
+		if actorID.Valid {
+			entry.ActorID = &actorID.UUID
+		}
+		if apiKeyID.Valid {
+			entry.APIKeyID = &apiKeyID.UUID
+		}
+		entry.ActorEmail = email.String
+		entry.RequestID = requestID.String
+		entry.Before, entry.After = before, after
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Purge удаляет записи журнала старше retention и возвращает их количество
+func (a AuditRepositoryImpl) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := a.db.ExecContext(ctx, "DELETE FROM audit_log WHERE created_at < $1", time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("[result.RowsAffected]: %w", err)
+	}
+	return n, nil
+}
+
+// auditEntry описывает изменение для журнала.
+// Source - таблица, из которой читается состояние сущности, по умолчанию Entity.
+// Для media это путешествие или место, к которому загружен файл
+type auditEntry struct {
+	Action   ds.AuditAction
+	Entity   ds.AuditEntity
+	Source   ds.AuditEntity
+	EntityID uuid.UUID
+}
+
+// auditSnapshots читают состояние сущности в JSON. Точки трека в журнал не пишутся - они слишком объёмные
+var auditSnapshots = map[ds.AuditEntity]string{
+	ds.AuditTravel: "SELECT to_jsonb(t) FROM travel t WHERE id = $1",
+	ds.AuditPlace:  "SELECT to_jsonb(p) FROM places p WHERE id = $1",
+	ds.AuditExpense: `SELECT to_jsonb(e) || jsonb_build_object('shares', (SELECT coalesce(jsonb_agg(jsonb_build_object('participant_id', s.participant_id, 'value', s.value) ORDER BY s.participant_id), '[]')
+		FROM expense_shares s WHERE s.expense_id = e.id)) FROM expenses e WHERE id = $1`,
+	ds.AuditLeg:   "SELECT to_jsonb(l) FROM legs l WHERE id = $1",
+	ds.AuditTrack: "SELECT to_jsonb(t) - 'segments' FROM tracks t WHERE id = $1",
+}
+
+// auditTravels находят путешествие, к которому относится сущность
+var auditTravels = map[ds.AuditEntity]string{
+	ds.AuditTravel:  "SELECT id FROM travel WHERE id = $1",
+	ds.AuditPlace:   "SELECT id FROM travel WHERE $1 = ANY(places) LIMIT 1",
+	ds.AuditExpense: "SELECT t.id FROM travel t JOIN places p ON p.id = ANY(t.places) WHERE p.expenses = $1 UNION SELECT travel_id FROM legs WHERE expenses = $1 LIMIT 1",
+	ds.AuditLeg:     "SELECT travel_id FROM legs WHERE id = $1",
+	ds.AuditTrack:   "SELECT travel_id FROM tracks WHERE id = $1",
+}
+
+// audited выполняет изменение change и записывает его в журнал в одной транзакции.
+// Состояние сущности читается до и после изменения, в журнал попадают только изменившиеся поля.
+// Путешествие определяется после изменения, а при удалении - до него: так в журнал
+// попадают и только что привязанные к путешествию места и расходы
+func audited(ctx context.Context, db *sqlx.DB, entry auditEntry, change func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = auditedTx(ctx, tx, entry, change)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return nil
+}
+
+// auditedTx - то же, что audited, внутри уже открытой транзакции: так несколько изменений
+// применяются и попадают в журнал вместе
+func auditedTx(ctx context.Context, tx *sqlx.Tx, entry auditEntry, change func(tx *sqlx.Tx) error) error {
+	if entry.Source == "" {
+		entry.Source = entry.Entity
+	}
+
+	var err error
+	var travelUUID uuid.UUID
+	var before, after []byte
+
+	if entry.Action == ds.AuditDelete {
+		err = tx.GetContext(ctx, &travelUUID, auditTravels[entry.Source], entry.EntityID)
+		if err != nil {
+			return fmt.Errorf("[tx.GetContext]: %w", notFound(err))
+		}
+	}
+
+	if entry.Action != ds.AuditCreate {
+		err = tx.GetContext(ctx, &before, auditSnapshots[entry.Source], entry.EntityID)
+		if err != nil {
+			return fmt.Errorf("[tx.GetContext]: %w", notFound(err))
+		}
+	}
+
+	err = change(tx)
+	if err != nil {
+		return err
+	}
+
+	if entry.Action != ds.AuditDelete {
+		err = tx.GetContext(ctx, &travelUUID, auditTravels[entry.Source], entry.EntityID)
+		if err != nil {
+			return fmt.Errorf("[tx.GetContext]: %w", notFound(err))
+		}
+
+		err = tx.GetContext(ctx, &after, auditSnapshots[entry.Source], entry.EntityID)
+		if err != nil {
+			return fmt.Errorf("[tx.GetContext]: %w", notFound(err))
+		}
+	}
+
+	before, after, err = auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	principal, _ := auth.FromContext(ctx)
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO audit_log (travel_id, actor_id, api_key_id, action, entity_type, entity_id, before, after, request_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		travelUUID, nullUUID(principal.UserID), nullUUID(principal.APIKeyID), entry.Action, entry.Entity, entry.EntityID,
+		nullJSON(before), nullJSON(after), nullString(requestid.FromContext(ctx)))
+	if err != nil {
+		return fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+	return nil
+}
+
+// auditDiff оставляет в состояниях до и после изменения только различающиеся поля.
+// Если одного из состояний нет (создание или удаление), другое возвращается целиком
+func auditDiff(before, after []byte) ([]byte, []byte, error) {
+	if before == nil || after == nil {
+		return before, after, nil
+	}
+
+	var old, cur map[string]json.RawMessage
+	if err := json.Unmarshal(before, &old); err != nil {
+		return nil, nil, fmt.Errorf("[json.Unmarshal]: %w", err)
+	}
+	if err := json.Unmarshal(after, &cur); err != nil {
+		return nil, nil, fmt.Errorf("[json.Unmarshal]: %w", err)
+	}
+
+	for field, value := range old {
+		if bytes.Equal(value, cur[field]) {
+			delete(old, field)
+			delete(cur, field)
+		}
+	}
+
+	before, err := json.Marshal(old)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[json.Marshal]: %w", err)
+	}
+	after, err = json.Marshal(cur)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[json.Marshal]: %w", err)
+	}
+	return before, after, nil
+}
+
+func nullJSON(data []byte) any {
+	if data == nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/ds"
+)
+
+const commentColumns = "c.id, c.travel_id, c.place_id, c.parent_id, c.author_id, u.email, c.body, c.deleted_at IS NOT NULL, c.created_at, c.updated_at"
+
+type CommentRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewCommentRepo(db *sqlx.DB) *CommentRepositoryImpl {
+	return &CommentRepositoryImpl{
+		db: db,
+	}
+}
+
+// GetComments - страница комментариев верхнего уровня к путешествию или, если задан placeUUID, к месту,
+// от старых к новым, с ответами и реакциями. after - id последнего комментария предыдущей страницы
+func (c CommentRepositoryImpl) GetComments(ctx context.Context, travelUUID, placeUUID, after uuid.UUID, limit int) ([]ds.Comment, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	travelUUID, err = c.commentTravel(ctx, travelUUID, placeUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := c.selectComments(ctx, "SELECT "+commentColumns+" FROM comments c LEFT JOIN users u ON u.id = c.author_id WHERE c.travel_id = $1 AND c.place_id IS NOT DISTINCT FROM $2 AND c.parent_id IS NULL AND ($3::uuid IS NULL OR (c.created_at, c.id) > (SELECT created_at, id FROM comments WHERE id = $3)) ORDER BY c.created_at, c.id LIMIT $4",
+		travelUUID, nullUUID(placeUUID), nullUUID(after), limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return comments, nil
+	}
+
+	ids := make([]uuid.UUID, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	replies, err := c.selectComments(ctx, "SELECT "+commentColumns+" FROM comments c LEFT JOIN users u ON u.id = c.author_id WHERE c.parent_id = ANY($1::uuid[]) ORDER BY c.created_at, c.id", uuidArray(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]*ds.Comment, 0, len(comments)+len(replies))
+	for i := range comments {
+		all = append(all, &comments[i])
+	}
+	for i := range replies {
+		all = append(all, &replies[i])
+		ids = append(ids, replies[i].ID)
+	}
+
+	err = c.loadReactions(ctx, userID, ids, all)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*ds.Comment, len(comments))
+	for i := range comments {
+		byID[comments[i].ID] = &comments[i]
+	}
+	for _, reply := range replies {
+		parent := byID[*reply.ParentID]
+		parent.Replies = append(parent.Replies, reply)
+	}
+
+	return comments, nil
+}
+
+// CreateComment оставляет комментарий от имени текущего пользователя. Комментировать может любой участник путешествия
+func (c CommentRepositoryImpl) CreateComment(ctx context.Context, comment ds.Comment) (ds.Comment, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Comment{}, err
+	}
+
+	var placeUUID uuid.UUID
+	if comment.PlaceID != nil {
+		placeUUID = *comment.PlaceID
+	}
+
+	comment.TravelID, err = c.commentTravel(ctx, comment.TravelID, placeUUID)
+	if err != nil {
+		return ds.Comment{}, err
+	}
+
+	// Читать комментарии можно и по публичной ссылке, писать - только участникам
+	_, err = travelRole(ctx, c.db, comment.TravelID)
+	if errors.Is(err, ErrNotFound) {
+		return ds.Comment{}, ErrForbidden
+	}
+	if err != nil {
+		return ds.Comment{}, err
+	}
+
+	// Отвечать можно только на комментарий верхнего уровня к тому же путешествию или месту
+	if comment.ParentID != nil {
+		var exists bool
+		err = c.db.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1 AND travel_id = $2 AND place_id IS NOT DISTINCT FROM $3 AND parent_id IS NULL)",
+			*comment.ParentID, comment.TravelID, nullUUID(placeUUID))
+		if err != nil {
+			return ds.Comment{}, fmt.Errorf("[db.GetContext]: %w", err)
+		}
+		if !exists {
+			return ds.Comment{}, ErrNotFound
+		}
+	}
+
+	comment.ID = uuid.New()
+	comment.AuthorID = &userID
+
+	err = c.db.GetContext(ctx, &comment.CreatedAt, "INSERT INTO comments (id, travel_id, place_id, parent_id, author_id, body) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at",
+		comment.ID, comment.TravelID, nullUUID(placeUUID), comment.ParentID, userID, comment.Body)
+	if err != nil {
+		return ds.Comment{}, fmt.Errorf("[db.GetContext]: %w", err)
+	}
+
+	comment.Reactions = []ds.Reaction{}
+	return comment, nil
+}
+
+// UpdateComment меняет текст комментария. Редактировать может только автор
+func (c CommentRepositoryImpl) UpdateComment(ctx context.Context, id uuid.UUID, body string) error {
+	userID, author, _, err := c.commentAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+	if author != userID {
+		return ErrForbidden
+	}
+
+	_, err = c.db.ExecContext(ctx, "UPDATE comments SET body = $1, updated_at = now() WHERE id = $2", body, id)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return nil
+}
+
+// DeleteComment стирает текст комментария, оставляя его в ветке. Удалить может автор или владелец путешествия
+func (c CommentRepositoryImpl) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	userID, author, role, err := c.commentAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+	if author != userID && !role.Allows(ds.RoleOwner) {
+		return ErrForbidden
+	}
+
+	_, err = c.db.ExecContext(ctx, "UPDATE comments SET body = '', deleted_at = now() WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+
+	_, err = c.db.ExecContext(ctx, "DELETE FROM comment_reactions WHERE comment_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return nil
+}
+
+// AddReaction ставит реакцию от имени текущего пользователя; повторная постановка ничего не меняет
+func (c CommentRepositoryImpl) AddReaction(ctx context.Context, commentID uuid.UUID, emoji string) error {
+	userID, _, _, err := c.commentAccess(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, "INSERT INTO comment_reactions (comment_id, user_id, emoji) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", commentID, userID, emoji)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return nil
+}
+
+func (c CommentRepositoryImpl) DeleteReaction(ctx context.Context, commentID uuid.UUID, emoji string) error {
+	userID, _, _, err := c.commentAccess(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.db.ExecContext(ctx, "DELETE FROM comment_reactions WHERE comment_id = $1 AND user_id = $2 AND emoji = $3", commentID, userID, emoji)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return affected(result)
+}
+
+// commentTravel возвращает путешествие, к которому относятся комментарии: travelUUID
+// или путешествие места placeUUID, если оно задано. Нужна роль не ниже viewer
+func (c CommentRepositoryImpl) commentTravel(ctx context.Context, travelUUID, placeUUID uuid.UUID) (uuid.UUID, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if placeUUID != uuid.Nil {
+		err = c.db.GetContext(ctx, &travelUUID, "SELECT t.id FROM travel t JOIN places p ON p.id = ANY(t.places) WHERE p.id = $1 AND p.id IN ("+placeScope(2, ds.RoleViewer)+")", placeUUID, userID)
+	} else {
+		err = c.db.GetContext(ctx, &travelUUID, "SELECT id FROM travel WHERE id = $1 AND id IN ("+travelScope(2, ds.RoleViewer)+")", travelUUID, userID)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("[db.GetContext]: %w", notFound(err))
+	}
+	return travelUUID, nil
+}
+
+// commentAccess возвращает текущего пользователя, автора комментария и роль пользователя в его путешествии.
+// Удалённые комментарии и комментарии к путешествиям и местам в корзине считаются ненайденными
+func (c CommentRepositoryImpl) commentAccess(ctx context.Context, id uuid.UUID) (uuid.UUID, uuid.UUID, ds.Role, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", err
+	}
+
+	var author uuid.NullUUID
+	var role ds.Role
+
+	err = c.db.QueryRowContext(ctx, "SELECT c.author_id, m.role FROM comments c JOIN travel_members m ON m.travel_id = c.travel_id AND m.user_id = $2 WHERE c.id = $1 AND c.deleted_at IS NULL AND c.travel_id IN ("+travelScope(2, ds.RoleViewer)+") AND (c.place_id IS NULL OR c.place_id IN ("+placeScope(2, ds.RoleViewer)+"))", id, userID).Scan(&author, &role)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+	return userID, author.UUID, role, nil
+}
+
+func (c CommentRepositoryImpl) selectComments(ctx context.Context, query string, args ...any) ([]ds.Comment, error) {
+	comments := []ds.Comment{}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment ds.Comment
+		var placeID, parentID, authorID uuid.NullUUID
+		var email sql.NullString
+		var updatedAt sql.NullTime
+
+		err := rows.Scan(&comment.ID, &comment.TravelID, &placeID, &parentID, &authorID, &email, &comment.Body, &comment.Deleted, &comment.CreatedAt, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+
+		comment.PlaceID = uuidPtr(placeID)
+		comment.ParentID = uuidPtr(parentID)
+		comment.AuthorID = uuidPtr(authorID)
+		comment.AuthorEmail = email.String
+		if updatedAt.Valid {
+			comment.UpdatedAt = &updatedAt.Time
+		}
+		comment.Reactions = []ds.Reaction{}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// loadReactions заполняет реакции комментариев в порядке, в котором их впервые поставили
+func (c CommentRepositoryImpl) loadReactions(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, comments []*ds.Comment) error {
+	byID := make(map[uuid.UUID]*ds.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	rows, err := c.db.QueryContext(ctx, "SELECT comment_id, emoji, count(*), bool_or(user_id = $2) FROM comment_reactions WHERE comment_id = ANY($1::uuid[]) GROUP BY comment_id, emoji ORDER BY min(created_at)", uuidArray(ids), userID)
+	if err != nil {
+		return fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID uuid.UUID
+		var reaction ds.Reaction
+
+		if err := rows.Scan(&commentID, &reaction.Emoji, &reaction.Count, &reaction.Reacted); err != nil {
+			return fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		if comment, ok := byID[commentID]; ok {
+			comment.Reactions = append(comment.Reactions, reaction)
+		}
+	}
+
+	return rows.Err()
+}
+
+func uuidPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose"
+)
+
+// testDB поднимает схему со всеми миграциями в отдельной schema базы из LTS_TEST_POSTGRES_DSN.
+// Без переменной тест пропускается
+func testDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv("LTS_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("LTS_TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sqlx.Connect() error = %v", err)
+	}
+	// search_path задаётся на соединение, поэтому оно одно
+	db.SetMaxOpenConns(1)
+
+	schema := "test_" + uuid.NewString()[:8]
+	_, err = db.Exec(fmt.Sprintf("CREATE SCHEMA %s; SET search_path TO %s, public", schema, schema))
+	if err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		db.Close()
+	})
+
+	err = goose.SetDialect("postgres")
+	if err != nil {
+		t.Fatalf("goose.SetDialect() error = %v", err)
+	}
+	err = goose.Up(db.DB, "../../../migrations")
+	if err != nil {
+		t.Fatalf("goose.Up() error = %v", err)
+	}
+	return db
+}
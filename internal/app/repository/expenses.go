@@ -18,43 +18,157 @@ func NewExpensesRepo(db *sqlx.DB) *ExpensesRepositoryImpl {
 	}
 }
 
+// CreateExpense создаёт расход без привязки. Доступ к нему появляется после SetExpenses места или перемещения
 func (e ExpensesRepositoryImpl) CreateExpense(ctx context.Context, expense ds.Expense) (ds.Expense, error) {
 	expense.ID = uuid.New()
+	if expense.SplitType == "" {
+		expense.SplitType = ds.SplitEqual
+	}
+
+	tx, err := e.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ds.Expense{}, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err := e.db.ExecContext(ctx, "INSERT INTO expenses VALUES ($1, $2, $3, $4, $5, $6)", expense.ID, expense.Road, expense.Residence, expense.Food, expense.Entertainment, expense.Other)
+	_, err = tx.ExecContext(ctx, "INSERT INTO expenses (id, road, residence, food, entertainment, other, payer, split_type) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		expense.ID, expense.Road, expense.Residence, expense.Food, expense.Entertainment, expense.Other, nullUUID(expense.Payer), expense.SplitType)
 	if err != nil {
-		return ds.Expense{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Expense{}, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	err = insertShares(ctx, tx, expense.ID, expense.Shares)
+	if err != nil {
+		return ds.Expense{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return ds.Expense{}, fmt.Errorf("[tx.Commit]: %w", err)
 	}
 	return expense, nil
 }
 
 func (e ExpensesRepositoryImpl) GetExpense(ctx context.Context, uuid uuid.UUID) (ds.Expense, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Expense{}, err
+	}
+
 	var expense ds.Expense
-	err := e.db.GetContext(ctx, &expense, "SELECT * FROM expenses WHERE id = $1", uuid)
+	err = e.db.QueryRowContext(ctx, "SELECT id, road, residence, food, entertainment, other, payer, split_type FROM expenses WHERE id = $1 AND id IN ("+expenseScope(2, ds.RoleViewer)+")", uuid, userID).Scan(
+		&expense.ID, &expense.Road, &expense.Residence, &expense.Food, &expense.Entertainment, &expense.Other, &expense.Payer, &expense.SplitType,
+	)
+	if err != nil {
+		return ds.Expense{}, fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+
+	expense.Shares, err = e.getShares(ctx, expense.ID)
 	if err != nil {
-		return ds.Expense{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Expense{}, err
 	}
 	return expense, nil
 }
 
 func (e ExpensesRepositoryImpl) UpdateExpense(ctx context.Context, expense ds.Expense, uuid uuid.UUID) (ds.Expense, error) {
-	_, err := e.db.ExecContext(ctx, "UPDATE expenses SET (road, residence, food, entertainment, other) = ($1, $2, $3, $4, $5) WHERE id = $6", expense.Road, expense.Residence, expense.Food, expense.Entertainment, expense.Other, uuid)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return ds.Expense{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Expense{}, err
 	}
 
-	err = e.db.GetContext(ctx, &expense, "SELECT * FROM expenses WHERE id = $1", uuid)
+	if expense.SplitType == "" {
+		expense.SplitType = ds.SplitEqual
+	}
+
+	err = audited(ctx, e.db, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditExpense, EntityID: uuid}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE expenses SET (road, residence, food, entertainment, other, payer, split_type) = ($1, $2, $3, $4, $5, $6, $7) WHERE id = $8 AND id IN ("+expenseScope(9, ds.RoleEditor)+")",
+			expense.Road, expense.Residence, expense.Food, expense.Entertainment, expense.Other, nullUUID(expense.Payer), expense.SplitType, uuid, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		err = affected(result)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM expense_shares WHERE expense_id = $1", uuid)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		return insertShares(ctx, tx, uuid, expense.Shares)
+	})
 	if err != nil {
-		return ds.Expense{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Expense{}, err
 	}
-	return expense, nil
+
+	return e.GetExpense(ctx, uuid)
 }
 
 func (e ExpensesRepositoryImpl) DeleteExpense(ctx context.Context, uuid uuid.UUID) error {
-	_, err := e.db.ExecContext(ctx, "DELETE FROM expenses WHERE id = $1", uuid)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
 
+	return audited(ctx, e.db, auditEntry{Action: ds.AuditDelete, Entity: ds.AuditExpense, EntityID: uuid}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM expenses WHERE id = $1 AND id IN ("+expenseScope(2, ds.RoleEditor)+")", uuid, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		return affected(result)
+	})
+}
+
+func (e ExpensesRepositoryImpl) getShares(ctx context.Context, expenseUUID uuid.UUID) ([]ds.ExpenseShare, error) {
+	shares := []ds.ExpenseShare{}
+
+	rows, err := e.db.QueryContext(ctx, "SELECT participant_id, value FROM expense_shares WHERE expense_id = $1", expenseUUID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var share ds.ExpenseShare
+		if err := rows.Scan(&share.ParticipantID, &share.Value); err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func insertShares(ctx context.Context, tx *sqlx.Tx, expenseUUID uuid.UUID, shares []ds.ExpenseShare) error {
+	for _, share := range shares {
+		_, err := tx.ExecContext(ctx, "INSERT INTO expense_shares (expense_id, participant_id, value) VALUES ($1, $2, $3)", expenseUUID, share.ParticipantID, share.Value)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+	}
 	return nil
 }
+
+// nullUUID превращает uuid.Nil в NULL, чтобы не нарушать внешние ключи
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+// GetExpenseTravel - путешествие, к месту или перемещению которого привязан расход
+func (e ExpensesRepositoryImpl) GetExpenseTravel(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var travelID uuid.UUID
+	err = e.db.GetContext(ctx, &travelID, "SELECT t.id FROM travel t JOIN places p ON p.id = ANY(t.places) WHERE p.expenses = $1 AND p.id IN ("+placeScope(2, ds.RoleViewer)+") "+
+		"UNION SELECT travel_id FROM legs WHERE expenses = $1 AND travel_id IN ("+travelScope(2, ds.RoleViewer)+") AND "+legLive+" LIMIT 1", id, userID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("[db.GetContext]: %w", notFound(err))
+	}
+	return travelID, nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+type HealthRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewHealthRepo(db *sqlx.DB) *HealthRepositoryImpl {
+	return &HealthRepositoryImpl{
+		db: db,
+	}
+}
+
+func (h HealthRepositoryImpl) Ping(ctx context.Context) error {
+	err := h.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("[db.PingContext]: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion - версия последней применённой миграции goose; откаченные миграции goose удаляет из таблицы
+func (h HealthRepositoryImpl) SchemaVersion(ctx context.Context) (int64, error) {
+	var version int64
+
+	err := h.db.GetContext(ctx, &version, "SELECT COALESCE(max(version_id), 0) FROM goose_db_version WHERE is_applied")
+	if err != nil {
+		return 0, fmt.Errorf("[db.GetContext]: %w", err)
+	}
+	return version, nil
+}
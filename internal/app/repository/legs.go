@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/ds"
+)
+
+const legColumns = "id, travel_id, from_place, to_place, mode, carrier, departure_at, departure_tz, arrival_at, arrival_tz, booking_ref, distance, expenses"
+
+type LegRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewLegRepo(db *sqlx.DB) *LegRepositoryImpl {
+	return &LegRepositoryImpl{
+		db: db,
+	}
+}
+
+func (l LegRepositoryImpl) CreateLeg(ctx context.Context, leg ds.Leg) (ds.Leg, error) {
+	err := checkTravel(ctx, l.db, leg.TravelID, ds.RoleEditor)
+	if err != nil {
+		return ds.Leg{}, err
+	}
+
+	leg.ID = uuid.New()
+	// Новое перемещение создаётся без расхода, расход привязывает SetExpenses
+	leg.Expenses = uuid.Nil
+
+	err = audited(ctx, l.db, auditEntry{Action: ds.AuditCreate, Entity: ds.AuditLeg, EntityID: leg.ID}, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO legs ("+legColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
+			leg.ID, leg.TravelID, leg.FromPlace, leg.ToPlace, leg.Mode, nullString(leg.Carrier), leg.DepartureAt, nullString(leg.DepartureTZ),
+			leg.ArrivalAt, nullString(leg.ArrivalTZ), nullString(leg.BookingRef), leg.Distance, nullUUID(leg.Expenses))
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ds.Leg{}, err
+	}
+	return leg, nil
+}
+
+func (l LegRepositoryImpl) GetLeg(ctx context.Context, id uuid.UUID) (ds.Leg, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Leg{}, err
+	}
+
+	leg, err := scanLeg(l.db.QueryRowContext(ctx, "SELECT "+legColumns+" FROM legs WHERE id = $1 AND travel_id IN ("+travelScope(2, ds.RoleViewer)+") AND "+legLive, id, userID))
+	if err != nil {
+		return ds.Leg{}, fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+	return leg, nil
+}
+
+func (l LegRepositoryImpl) GetLegs(ctx context.Context, travelUUID uuid.UUID) ([]ds.Leg, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	legs := []ds.Leg{}
+
+	rows, err := l.db.QueryContext(ctx, "SELECT "+legColumns+" FROM legs WHERE travel_id = $1 AND travel_id IN ("+travelScope(2, ds.RoleViewer)+") AND "+legLive+" ORDER BY departure_at NULLS LAST", travelUUID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		leg, err := scanLeg(rows)
+		if err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		legs = append(legs, leg)
+	}
+
+	return legs, rows.Err()
+}
+
+func (l LegRepositoryImpl) UpdateLeg(ctx context.Context, id uuid.UUID, leg ds.Leg) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return audited(ctx, l.db, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditLeg, EntityID: id}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE legs SET (from_place, to_place, mode, carrier, departure_at, departure_tz, arrival_at, arrival_tz, booking_ref, distance) = ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) WHERE id = $11 AND travel_id IN ("+travelScope(12, ds.RoleEditor)+") AND "+legLive,
+			leg.FromPlace, leg.ToPlace, leg.Mode, nullString(leg.Carrier), leg.DepartureAt, nullString(leg.DepartureTZ),
+			leg.ArrivalAt, nullString(leg.ArrivalTZ), nullString(leg.BookingRef), leg.Distance, id, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
+}
+
+func (l LegRepositoryImpl) SetExpenses(ctx context.Context, uuidExpense, uuidLeg uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return audited(ctx, l.db, auditEntry{Action: ds.AuditCreate, Entity: ds.AuditExpense, EntityID: uuidExpense}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE legs SET expenses = $1 WHERE id = $2 AND travel_id IN ("+travelScope(3, ds.RoleEditor)+") AND "+legLive, uuidExpense, uuidLeg, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
+}
+
+// DeleteLeg удаляет перемещение вместе с его расходом в одной транзакции.
+// Расход доступен только через перемещение, без него он остался бы недостижимым
+func (l LegRepositoryImpl) DeleteLeg(ctx context.Context, id uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	var expense uuid.NullUUID
+
+	err = tx.GetContext(ctx, &expense, "SELECT expenses FROM legs WHERE id = $1 AND travel_id IN ("+travelScope(2, ds.RoleEditor)+") AND "+legLive+" FOR UPDATE", id, userID)
+	if err != nil {
+		return fmt.Errorf("[tx.GetContext]: %w", notFound(err))
+	}
+
+	if expense.Valid {
+		err = auditedTx(ctx, tx, auditEntry{Action: ds.AuditDelete, Entity: ds.AuditExpense, EntityID: expense.UUID}, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM expenses WHERE id = $1", expense.UUID)
+			if err != nil {
+				return fmt.Errorf("[tx.ExecContext]: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = auditedTx(ctx, tx, auditEntry{Action: ds.AuditDelete, Entity: ds.AuditLeg, EntityID: id}, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM legs WHERE id = $1", id)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLeg(row rowScanner) (ds.Leg, error) {
+	var leg ds.Leg
+	var carrier, departureTZ, arrivalTZ, bookingRef sql.NullString
+	var distance sql.NullFloat64
+
+	err := row.Scan(&leg.ID, &leg.TravelID, &leg.FromPlace, &leg.ToPlace, &leg.Mode, &carrier, &leg.DepartureAt, &departureTZ,
+		&leg.ArrivalAt, &arrivalTZ, &bookingRef, &distance, &leg.Expenses)
+	if err != nil {
+		return ds.Leg{}, err
+	}
+
+	leg.Carrier = carrier.String
+	leg.DepartureTZ = departureTZ.String
+	leg.ArrivalTZ = arrivalTZ.String
+	leg.BookingRef = bookingRef.String
+	if distance.Valid {
+		leg.Distance = &distance.Float64
+	}
+	leg.Localize()
+
+	return leg, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+)
+
+func TestDeleteLeg(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	users := NewUserRepo(db)
+	owner, err := users.CreateUser(ctx, ds.User{Email: "owner@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	viewer, err := users.CreateUser(ctx, ds.User{Email: "viewer@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	ownerCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: owner.ID})
+	viewerCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: viewer.ID})
+
+	travels := NewTravelRepo(db)
+	travel, err := travels.CreateTravel(ownerCtx, ds.Travel{Name: "Summer"})
+	if err != nil {
+		t.Fatalf("CreateTravel() error = %v", err)
+	}
+	_, err = db.Exec("INSERT INTO travel_members (travel_id, user_id, role) VALUES ($1, $2, $3)", travel.ID, viewer.ID, ds.RoleViewer)
+	if err != nil {
+		t.Fatalf("insert member: %v", err)
+	}
+
+	places := NewPlaceRepositoryImpl(db)
+	var stops []ds.Place
+	for _, name := range []string{"Moscow", "Kazan"} {
+		place, err := places.CreatePlace(ownerCtx, ds.Place{Name: name})
+		if err != nil {
+			t.Fatalf("CreatePlace() error = %v", err)
+		}
+		err = travels.AddPlace(ownerCtx, travel.ID, place.ID)
+		if err != nil {
+			t.Fatalf("AddPlace() error = %v", err)
+		}
+		stops = append(stops, place)
+	}
+
+	legs := NewLegRepo(db)
+	leg, err := legs.CreateLeg(ownerCtx, ds.Leg{TravelID: travel.ID, FromPlace: stops[0].ID, ToPlace: stops[1].ID, Mode: "train"})
+	if err != nil {
+		t.Fatalf("CreateLeg() error = %v", err)
+	}
+
+	expenses := NewExpensesRepo(db)
+	expense, err := expenses.CreateExpense(ownerCtx, ds.Expense{Road: 50})
+	if err != nil {
+		t.Fatalf("CreateExpense() error = %v", err)
+	}
+	err = legs.SetExpenses(ownerCtx, expense.ID, leg.ID)
+	if err != nil {
+		t.Fatalf("SetExpenses() error = %v", err)
+	}
+
+	err = legs.DeleteLeg(viewerCtx, leg.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteLeg() by viewer error = %v, want %v", err, ErrNotFound)
+	}
+	_, err = expenses.GetExpense(ownerCtx, expense.ID)
+	if err != nil {
+		t.Fatalf("expense of a leg that was not deleted: GetExpense() error = %v", err)
+	}
+
+	err = legs.DeleteLeg(ownerCtx, leg.ID)
+	if err != nil {
+		t.Fatalf("DeleteLeg() error = %v", err)
+	}
+
+	_, err = legs.GetLeg(ownerCtx, leg.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetLeg() after delete error = %v, want %v", err, ErrNotFound)
+	}
+
+	var left int
+	err = db.Get(&left, "SELECT count(*) FROM expenses WHERE id = $1", expense.ID)
+	if err != nil {
+		t.Fatalf("count expenses: %v", err)
+	}
+	if left != 0 {
+		t.Fatalf("expense of the deleted leg is kept")
+	}
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/ds"
+	"strings"
+	"time"
+)
+
+type MemberRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewMemberRepo(db *sqlx.DB) *MemberRepositoryImpl {
+	return &MemberRepositoryImpl{
+		db: db,
+	}
+}
+
+func (m MemberRepositoryImpl) GetRole(ctx context.Context, travelUUID uuid.UUID) (ds.Role, error) {
+	return travelRole(ctx, m.db, travelUUID)
+}
+
+// GetPlaceRole - роль текущего пользователя в путешествии, к которому относится место
+func (m MemberRepositoryImpl) GetPlaceRole(ctx context.Context, placeUUID uuid.UUID) (ds.Role, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var role ds.Role
+	err = m.db.GetContext(ctx, &role, "SELECT m.role FROM travel_members m JOIN travel t ON t.id = m.travel_id JOIN places p ON p.id = ANY(t.places) WHERE p.id = $1 AND m.user_id = $2 AND p.deleted_at IS NULL AND t.deleted_at IS NULL", placeUUID, userID)
+	if err != nil {
+		return "", fmt.Errorf("[db.GetContext]: %w", notFound(err))
+	}
+	return role, nil
+}
+
+func (m MemberRepositoryImpl) GetMembers(ctx context.Context, travelUUID uuid.UUID) ([]ds.Member, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []ds.Member{}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT u.id, u.email, coalesce(u.name, ''), m.role, m.created_at FROM travel_members m JOIN users u ON u.id = m.user_id WHERE m.travel_id = $1 AND m.travel_id IN ("+travelScope(2, ds.RoleViewer)+") ORDER BY m.created_at", travelUUID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member ds.Member
+		if err := rows.Scan(&member.UserID, &member.Email, &member.Name, &member.Role, &member.CreatedAt); err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("[rows.Err]: %w", err)
+	}
+
+	// Пустой список означает, что путешествие недоступно пользователю
+	if len(members) == 0 {
+		return nil, ErrNotFound
+	}
+	return members, nil
+}
+
+func (m MemberRepositoryImpl) CreateInvite(ctx context.Context, invite ds.Invite, tokenHash string) (ds.Invite, error) {
+	err := checkTravel(ctx, m.db, invite.TravelID, ds.RoleOwner)
+	if err != nil {
+		return ds.Invite{}, err
+	}
+
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Invite{}, err
+	}
+
+	invite.ID = uuid.New()
+	invite.Email = strings.ToLower(strings.TrimSpace(invite.Email))
+
+	_, err = m.db.ExecContext(ctx, "INSERT INTO travel_invites (id, travel_id, email, role, token_hash, invited_by, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		invite.ID, invite.TravelID, invite.Email, invite.Role, tokenHash, userID, invite.ExpiresAt)
+	if err != nil {
+		return ds.Invite{}, fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return invite, nil
+}
+
+// AcceptInvite добавляет текущего пользователя в путешествие. Приглашение одноразовое
+// и принимается только пользователем с тем email, на который оно выписано
+func (m MemberRepositoryImpl) AcceptInvite(ctx context.Context, tokenHash string) (ds.Invite, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Invite{}, err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ds.Invite{}, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	var invite ds.Invite
+	err = tx.QueryRowContext(ctx, "SELECT i.id, i.travel_id, i.email, i.role, i.expires_at FROM travel_invites i JOIN users u ON lower(u.email) = i.email WHERE i.token_hash = $1 AND u.id = $2 AND i.accepted_at IS NULL FOR UPDATE OF i", tokenHash, userID).Scan(
+		&invite.ID, &invite.TravelID, &invite.Email, &invite.Role, &invite.ExpiresAt,
+	)
+	if err != nil {
+		return ds.Invite{}, fmt.Errorf("[tx.QueryRowContext]: %w", notFound(err))
+	}
+
+	if time.Now().After(invite.ExpiresAt) {
+		return ds.Invite{}, ErrNotFound
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO travel_members (travel_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", invite.TravelID, userID, invite.Role)
+	if err != nil {
+		return ds.Invite{}, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return ds.Invite{}, fmt.Errorf("[result.RowsAffected]: %w", err)
+	}
+	if n == 0 {
+		return ds.Invite{}, ErrConflict
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE travel_invites SET accepted_at = now() WHERE id = $1", invite.ID)
+	if err != nil {
+		return ds.Invite{}, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return ds.Invite{}, fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return invite, nil
+}
+
+func (m MemberRepositoryImpl) UpdateMemberRole(ctx context.Context, travelUUID, userUUID uuid.UUID, role ds.Role) error {
+	err := checkTravel(ctx, m.db, travelUUID, ds.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	return m.changeMembers(ctx, travelUUID, "UPDATE travel_members SET role = $3 WHERE travel_id = $1 AND user_id = $2", travelUUID, userUUID, role)
+}
+
+// DeleteMember удаляет участника. Владелец может удалить любого, остальные - только себя
+func (m MemberRepositoryImpl) DeleteMember(ctx context.Context, travelUUID, userUUID uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	required := ds.RoleOwner
+	if userUUID == userID {
+		required = ds.RoleViewer
+	}
+
+	err = checkTravel(ctx, m.db, travelUUID, required)
+	if err != nil {
+		return err
+	}
+
+	return m.changeMembers(ctx, travelUUID, "DELETE FROM travel_members WHERE travel_id = $1 AND user_id = $2", travelUUID, userUUID)
+}
+
+// changeMembers выполняет изменение состава участников и откатывает его,
+// если в путешествии не осталось ни одного владельца
+func (m MemberRepositoryImpl) changeMembers(ctx context.Context, travelUUID uuid.UUID, query string, args ...any) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокируем путешествие, чтобы два владельца не разжаловали друг друга одновременно
+	_, err = tx.ExecContext(ctx, "SELECT 1 FROM travel WHERE id = $1 FOR UPDATE", travelUUID)
+	if err != nil {
+		return fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	err = affected(result)
+	if err != nil {
+		return err
+	}
+
+	var owners int
+	err = tx.GetContext(ctx, &owners, "SELECT count(*) FROM travel_members WHERE travel_id = $1 AND role = $2", travelUUID, ds.RoleOwner)
+	if err != nil {
+		return fmt.Errorf("[tx.GetContext]: %w", err)
+	}
+	if owners == 0 {
+		return fmt.Errorf("travel must keep at least one owner: %w", ErrConflict)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return nil
+}
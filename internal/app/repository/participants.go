@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"lts/internal/app/ds"
+)
+
+type ParticipantRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewParticipantRepo(db *sqlx.DB) *ParticipantRepositoryImpl {
+	return &ParticipantRepositoryImpl{
+		db: db,
+	}
+}
+
+func (p ParticipantRepositoryImpl) CreateParticipant(ctx context.Context, participant ds.Participant) (ds.Participant, error) {
+	err := checkTravel(ctx, p.db, participant.TravelID, ds.RoleEditor)
+	if err != nil {
+		return ds.Participant{}, err
+	}
+
+	participant.ID = uuid.New()
+
+	_, err = p.db.ExecContext(ctx, "INSERT INTO participants (id, travel_id, name) VALUES ($1, $2, $3)", participant.ID, participant.TravelID, participant.Name)
+	if err != nil {
+		return ds.Participant{}, fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return participant, nil
+}
+
+func (p ParticipantRepositoryImpl) GetParticipants(ctx context.Context, travelUUID uuid.UUID) ([]ds.Participant, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := []ds.Participant{}
+
+	rows, err := p.db.QueryContext(ctx, "SELECT id, travel_id, name FROM participants WHERE travel_id = $1 AND travel_id IN ("+travelScope(2, ds.RoleViewer)+") ORDER BY name", travelUUID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var participant ds.Participant
+		if err := rows.Scan(&participant.ID, &participant.TravelID, &participant.Name); err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		participants = append(participants, participant)
+	}
+
+	return participants, rows.Err()
+}
+
+func (p ParticipantRepositoryImpl) DeleteParticipant(ctx context.Context, travelUUID, participantUUID uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Участника, который платит за расходы или участвует в них, не даёт удалить внешний ключ,
+	// проверка заранее нужна для понятного ответа вместо ошибки БД
+	var used bool
+	err = p.db.GetContext(ctx, &used, "SELECT EXISTS (SELECT 1 FROM expense_shares WHERE participant_id = $1) OR EXISTS (SELECT 1 FROM expenses WHERE payer = $1) "+
+		"FROM participants WHERE id = $1 AND travel_id = $2 AND travel_id IN ("+travelScope(3, ds.RoleEditor)+")", participantUUID, travelUUID, userID)
+	if err != nil {
+		return fmt.Errorf("[db.GetContext]: %w", notFound(err))
+	}
+	if used {
+		return fmt.Errorf("participant pays for or shares expenses, update them first: %w", ErrConflict)
+	}
+
+	result, err := p.db.ExecContext(ctx, "DELETE FROM participants WHERE id = $1 AND travel_id = $2 AND travel_id IN ("+travelScope(3, ds.RoleEditor)+")", participantUUID, travelUUID, userID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return fmt.Errorf("participant pays for or shares expenses, update them first: %w", ErrConflict)
+		}
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return affected(result)
+}
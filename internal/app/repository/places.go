@@ -18,71 +18,173 @@ func NewPlaceRepositoryImpl(db *sqlx.DB) *PlaceRepositoryImpl {
 	return &PlaceRepositoryImpl{db: db}
 }
 
+// CreatePlace создаёт место без привязки к путешествию.
+// Доступ к нему появляется после TravelRepository.AddPlace, поэтому права проверяются там
 func (p PlaceRepositoryImpl) CreatePlace(ctx context.Context, place ds.Place) (ds.Place, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ds.Place{}, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	place, err = p.CreatePlaceTx(ctx, tx, place)
+	if err != nil {
+		return ds.Place{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return ds.Place{}, fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return place, nil
+}
+
+// CreatePlaceTx - CreatePlace в транзакции вызывающего, например импорта GPX
+func (p PlaceRepositoryImpl) CreatePlaceTx(ctx context.Context, tx *sqlx.Tx, place ds.Place) (ds.Place, error) {
 	place.ID = uuid.New()
 
-	_, err := p.db.ExecContext(ctx, "INSERT INTO places VALUES ($1, $2, $3, $4)", place.ID, place.Name, place.Story, place.Date.Time)
+	_, err := tx.ExecContext(ctx, "INSERT INTO places (id, name, story, date, latitude, longitude, address, country_code) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		place.ID, place.Name, place.Story, place.Date.Time, place.Latitude, place.Longitude, nullString(place.Address), nullString(place.CountryCode))
+	if err != nil {
+		return ds.Place{}, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	err = addRevision(ctx, tx, ds.RevisionPlace, place.ID, place.Story)
 	if err != nil {
-		return ds.Place{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Place{}, err
 	}
 	return place, nil
 }
 
 func (p PlaceRepositoryImpl) SetExpenses(ctx context.Context, uuidExpense, uuidPlace uuid.UUID) error {
-	_, err := p.db.ExecContext(ctx, "UPDATE places SET expenses = $1 WHERE id = $2", uuidExpense, uuidPlace)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
-	return nil
+
+	return audited(ctx, p.db, auditEntry{Action: ds.AuditCreate, Entity: ds.AuditExpense, EntityID: uuidExpense}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE places SET expenses = $1 WHERE id = $2 AND id IN ("+placeScope(3, ds.RoleEditor)+")", uuidExpense, uuidPlace, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
 }
 
 func (p PlaceRepositoryImpl) SetPreview(ctx context.Context, path string, uuid uuid.UUID) error {
-	_, err := p.db.ExecContext(ctx, "UPDATE places SET preview = $1 WHERE id = $2", path, uuid)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
-	return nil
+
+	return audited(ctx, p.db, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditMedia, Source: ds.AuditPlace, EntityID: uuid}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE places SET preview = $1 WHERE id = $2 AND id IN ("+placeScope(3, ds.RoleEditor)+")", path, uuid, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
 }
 
 func (p PlaceRepositoryImpl) SetImages(ctx context.Context, paths []string, uuid uuid.UUID) error {
-	_, err := p.db.ExecContext(ctx, "UPDATE places SET images = $1 WHERE id = $2", pq.StringArray(paths), uuid)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
-	return nil
+
+	return audited(ctx, p.db, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditMedia, Source: ds.AuditPlace, EntityID: uuid}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE places SET images = $1 WHERE id = $2 AND id IN ("+placeScope(3, ds.RoleEditor)+")", pq.StringArray(paths), uuid, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
 }
 
+// DeletePlace перемещает место в корзину. Файлы и расходы места остаются до очистки корзины
 func (p PlaceRepositoryImpl) DeletePlace(ctx context.Context, uuid uuid.UUID) error {
-	_, err := p.db.ExecContext(ctx, "DELETE FROM places WHERE id = $1", uuid)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
-	return nil
+
+	return audited(ctx, p.db, auditEntry{Action: ds.AuditDelete, Entity: ds.AuditPlace, EntityID: uuid}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE places SET deleted_at = now(), deleted_by = $2 WHERE id = $1 AND id IN ("+placeScope(2, ds.RoleEditor)+")", uuid, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
 }
 
 func (p PlaceRepositoryImpl) UpdatePlace(ctx context.Context, id uuid.UUID, place ds.Place) error {
-	_, err := p.db.ExecContext(ctx, "UPDATE places SET (name, story, date) = ($1, $2, $3) WHERE id = $4", place.Name, place.Story, place.Date.Time, id)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
 
-	return nil
+	return audited(ctx, p.db, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditPlace, EntityID: id}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE places SET (name, story, date, latitude, longitude, address, country_code) = ($1, $2, $3, $4, $5, $6, $7) WHERE id = $8 AND id IN ("+placeScope(9, ds.RoleEditor)+")",
+			place.Name, place.Story, place.Date.Time, place.Latitude, place.Longitude, nullString(place.Address), nullString(place.CountryCode), id, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		err = affected(result)
+		if err != nil {
+			return err
+		}
+
+		return addRevision(ctx, tx, ds.RevisionPlace, id, place.Story)
+	})
 }
 
 func (p PlaceRepositoryImpl) GetPlace(ctx context.Context, id uuid.UUID) (ds.Place, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Place{}, err
+	}
+
 	place := ds.Place{}
 
-	var preview sql.NullString
+	var preview, address, countryCode sql.NullString
+	var latitude, longitude sql.NullFloat64
 
-	err := p.db.QueryRowContext(ctx, "SELECT id, name, story, date, images, expenses, preview FROM places WHERE id = $1", id).Scan(
-		&place.ID, &place.Name, &place.Story, &place.Date.Time, &place.Images, &place.Expenses, &preview,
+	err = p.db.QueryRowContext(ctx, "SELECT id, name, story, date, images, expenses, preview, latitude, longitude, address, country_code FROM places WHERE id = $1 AND id IN ("+placeScope(2, ds.RoleViewer)+")", id, userID).Scan(
+		&place.ID, &place.Name, &place.Story, &place.Date.Time, &place.Images, &place.Expenses, &preview, &latitude, &longitude, &address, &countryCode,
 	)
 	if err != nil {
-		return ds.Place{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Place{}, fmt.Errorf("[db.ExecContext]: %w", notFound(err))
 	}
 
 	place.Preview = preview.String
+	place.Address = address.String
+	place.CountryCode = countryCode.String
+	if latitude.Valid && longitude.Valid {
+		place.Latitude = &latitude.Float64
+		place.Longitude = &longitude.Float64
+	}
 
 	return place, nil
 
 }
+
+// nullString превращает пустую строку в NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// GetPlaceTravel - путешествие, которому принадлежит место
+func (p PlaceRepositoryImpl) GetPlaceTravel(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var travelID uuid.UUID
+	err = p.db.GetContext(ctx, &travelID, "SELECT t.id FROM travel t JOIN places p ON p.id = ANY(t.places) WHERE p.id = $1 AND p.id IN ("+placeScope(2, ds.RoleViewer)+")", id, userID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("[db.GetContext]: %w", notFound(err))
+	}
+	return travelID, nil
+}
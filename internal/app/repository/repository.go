@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"github.com/google/uuid"
+	"lts/internal/app/auth"
 	"lts/internal/app/ds"
+	"time"
 )
 
 type TravelRepository interface {
@@ -14,6 +16,11 @@ type TravelRepository interface {
 	UpdateTravel(ctx context.Context, id uuid.UUID, travel ds.Travel) error
 	DeleteTravel(ctx context.Context, id uuid.UUID) error
 	GetAllTravels(ctx context.Context) ([]ds.TravelCard, error)
+	GetStorageUsage(ctx context.Context, travelUUID uuid.UUID) (int64, error)
+	AddFile(ctx context.Context, file ds.StoredFile) error
+	HasFiles(ctx context.Context) (bool, error)
+	IndexFiles(ctx context.Context, files []ds.StoredFile) (int64, error)
+	ImportGPX(ctx context.Context, travelUUID uuid.UUID, imported ds.TrackImport) (ds.TrackImport, error)
 }
 
 type PlaceRepository interface {
@@ -24,6 +31,7 @@ type PlaceRepository interface {
 	DeletePlace(ctx context.Context, uuid uuid.UUID) error
 	UpdatePlace(ctx context.Context, id uuid.UUID, place ds.Place) error
 	GetPlace(ctx context.Context, id uuid.UUID) (ds.Place, error)
+	GetPlaceTravel(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
 }
 
 type ExpensesRepository interface {
@@ -31,4 +39,96 @@ type ExpensesRepository interface {
 	GetExpense(ctx context.Context, uuid uuid.UUID) (ds.Expense, error)
 	UpdateExpense(ctx context.Context, expense ds.Expense, uuid uuid.UUID) (ds.Expense, error)
 	DeleteExpense(ctx context.Context, uuid uuid.UUID) error
+	GetExpenseTravel(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+}
+
+type ParticipantRepository interface {
+	CreateParticipant(ctx context.Context, participant ds.Participant) (ds.Participant, error)
+	GetParticipants(ctx context.Context, travelUUID uuid.UUID) ([]ds.Participant, error)
+	DeleteParticipant(ctx context.Context, travelUUID, participantUUID uuid.UUID) error
+}
+
+type TrackRepository interface {
+	CreateTrack(ctx context.Context, track ds.Track) (ds.Track, error)
+	GetTracks(ctx context.Context, travelUUID uuid.UUID) ([]ds.Track, error)
+}
+
+type LegRepository interface {
+	CreateLeg(ctx context.Context, leg ds.Leg) (ds.Leg, error)
+	GetLeg(ctx context.Context, id uuid.UUID) (ds.Leg, error)
+	GetLegs(ctx context.Context, travelUUID uuid.UUID) ([]ds.Leg, error)
+	UpdateLeg(ctx context.Context, id uuid.UUID, leg ds.Leg) error
+	SetExpenses(ctx context.Context, uuidExpense, uuidLeg uuid.UUID) error
+	DeleteLeg(ctx context.Context, id uuid.UUID) error
+}
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user ds.User) (ds.User, error)
+	GetUserByEmail(ctx context.Context, email string) (ds.User, error)
+	GetUser(ctx context.Context, id uuid.UUID) (ds.User, error)
+	AdoptOrphanTravels(ctx context.Context, email string) (int64, error)
+}
+
+type MemberRepository interface {
+	GetRole(ctx context.Context, travelUUID uuid.UUID) (ds.Role, error)
+	GetPlaceRole(ctx context.Context, placeUUID uuid.UUID) (ds.Role, error)
+	GetMembers(ctx context.Context, travelUUID uuid.UUID) ([]ds.Member, error)
+	CreateInvite(ctx context.Context, invite ds.Invite, tokenHash string) (ds.Invite, error)
+	AcceptInvite(ctx context.Context, tokenHash string) (ds.Invite, error)
+	UpdateMemberRole(ctx context.Context, travelUUID, userUUID uuid.UUID, role ds.Role) error
+	DeleteMember(ctx context.Context, travelUUID, userUUID uuid.UUID) error
+}
+
+type ShareRepository interface {
+	CreateShare(ctx context.Context, share ds.ShareLink, tokenHash string) (ds.ShareLink, error)
+	GetShares(ctx context.Context, travelUUID uuid.UUID) ([]ds.ShareLink, error)
+	RevokeShare(ctx context.Context, travelUUID, shareUUID uuid.UUID) error
+	ResolveShare(ctx context.Context, tokenHash string) (ds.ShareLink, error)
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key ds.APIKey, keyHash string) (ds.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]ds.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+	Authenticate(ctx context.Context, keyHash string) (auth.Principal, error)
+}
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token ds.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, oldHash string, next ds.RefreshToken) (ds.User, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+}
+
+type AuditRepository interface {
+	GetHistory(ctx context.Context, travelUUID uuid.UUID, before int64, limit int) ([]ds.AuditEntry, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+type TrashRepository interface {
+	GetTrashedTravels(ctx context.Context) ([]ds.TrashItem, error)
+	GetTrashedPlaces(ctx context.Context, travelUUID uuid.UUID) ([]ds.TrashItem, error)
+	RestoreTravel(ctx context.Context, id uuid.UUID) error
+	RestorePlace(ctx context.Context, travelUUID, placeUUID uuid.UUID) error
+	PurgeTravels(ctx context.Context, before time.Time) ([]uuid.UUID, error)
+	PurgePlaces(ctx context.Context, before time.Time) ([]ds.TrashItem, error)
+}
+
+type RevisionRepository interface {
+	GetRevisions(ctx context.Context, entity ds.RevisionEntity, id uuid.UUID) ([]ds.Revision, error)
+	GetRevision(ctx context.Context, entity ds.RevisionEntity, id uuid.UUID, revisionID int64) (ds.Revision, error)
+	RestoreRevision(ctx context.Context, entity ds.RevisionEntity, id uuid.UUID, revisionID int64) error
+}
+
+type CommentRepository interface {
+	GetComments(ctx context.Context, travelUUID, placeUUID, after uuid.UUID, limit int) ([]ds.Comment, error)
+	CreateComment(ctx context.Context, comment ds.Comment) (ds.Comment, error)
+	UpdateComment(ctx context.Context, id uuid.UUID, body string) error
+	DeleteComment(ctx context.Context, id uuid.UUID) error
+	AddReaction(ctx context.Context, commentID uuid.UUID, emoji string) error
+	DeleteReaction(ctx context.Context, commentID uuid.UUID, emoji string) error
+}
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int64, error)
 }
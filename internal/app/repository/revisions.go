@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+	"lts/internal/app/textdiff"
+)
+
+// revisionTarget - где хранится текст сущности и как проверяется доступ к ней
+type revisionTarget struct {
+	table  string
+	column string
+	scope  func(arg int, role ds.Role) string
+	audit  ds.AuditEntity
+}
+
+var revisionTargets = map[ds.RevisionEntity]revisionTarget{
+	ds.RevisionTravel: {table: "travel", column: "description", scope: travelScope, audit: ds.AuditTravel},
+	ds.RevisionPlace:  {table: "places", column: "story", scope: placeScope, audit: ds.AuditPlace},
+}
+
+const revisionColumns = "r.id, r.entity_type, r.entity_id, r.author_id, u.email, r.body, r.diff, r.created_at"
+
+type RevisionRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewRevisionRepo(db *sqlx.DB) *RevisionRepositoryImpl {
+	return &RevisionRepositoryImpl{
+		db: db,
+	}
+}
+
+// GetRevisions - ревизии текста сущности от новых к старым
+func (rr RevisionRepositoryImpl) GetRevisions(ctx context.Context, entity ds.RevisionEntity, id uuid.UUID) ([]ds.Revision, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := revisionTargets[entity]
+
+	var exists bool
+	err = rr.db.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM "+target.table+" WHERE id = $1 AND id IN ("+target.scope(2, ds.RoleViewer)+"))", id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.GetContext]: %w", err)
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	revisions := []ds.Revision{}
+
+	rows, err := rr.db.QueryContext(ctx, "SELECT "+revisionColumns+" FROM revisions r LEFT JOIN users u ON u.id = r.author_id WHERE r.entity_type = $1 AND r.entity_id = $2 ORDER BY r.id DESC", entity, id)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (rr RevisionRepositoryImpl) GetRevision(ctx context.Context, entity ds.RevisionEntity, id uuid.UUID, revisionID int64) (ds.Revision, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Revision{}, err
+	}
+
+	target := revisionTargets[entity]
+
+	revision, err := scanRevision(rr.db.QueryRowContext(ctx, "SELECT "+revisionColumns+" FROM revisions r LEFT JOIN users u ON u.id = r.author_id WHERE r.id = $1 AND r.entity_type = $2 AND r.entity_id = $3 AND r.entity_id IN ("+target.scope(4, ds.RoleViewer)+")",
+		revisionID, entity, id, userID))
+	if err != nil {
+		return ds.Revision{}, fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+	return revision, nil
+}
+
+// RestoreRevision делает текст ревизии текущим. Восстановление сохраняется как новая ревизия
+func (rr RevisionRepositoryImpl) RestoreRevision(ctx context.Context, entity ds.RevisionEntity, id uuid.UUID, revisionID int64) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	target := revisionTargets[entity]
+
+	return audited(ctx, rr.db, auditEntry{Action: ds.AuditUpdate, Entity: target.audit, EntityID: id}, func(tx *sqlx.Tx) error {
+		var body string
+		err := tx.GetContext(ctx, &body, "UPDATE "+target.table+" SET "+target.column+" = r.body FROM revisions r WHERE r.id = $1 AND r.entity_type = $2 AND r.entity_id = $3 AND "+target.table+".id = $3 AND "+target.table+".id IN ("+target.scope(4, ds.RoleEditor)+") RETURNING r.body",
+			revisionID, entity, id, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.GetContext]: %w", notFound(err))
+		}
+
+		return addRevision(ctx, tx, entity, id, body)
+	})
+}
+
+// addRevision сохраняет текст как новую ревизию, если он отличается от последней.
+// Вызывается в транзакции, изменившей текст: строка сущности уже заблокирована,
+// поэтому параллельные изменения выстраиваются в очередь и разница считается от верной ревизии
+func addRevision(ctx context.Context, tx *sqlx.Tx, entity ds.RevisionEntity, id uuid.UUID, body string) error {
+	var last string
+	err := tx.GetContext(ctx, &last, "SELECT body FROM revisions WHERE entity_type = $1 AND entity_id = $2 ORDER BY id DESC LIMIT 1", entity, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("[tx.GetContext]: %w", err)
+	}
+	if last == body {
+		return nil
+	}
+
+	principal, _ := auth.FromContext(ctx)
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO revisions (entity_type, entity_id, author_id, body, diff) VALUES ($1, $2, $3, $4, $5)",
+		entity, id, nullUUID(principal.UserID), body, textdiff.Unified(last, body, textdiff.DefaultContext))
+	if err != nil {
+		return fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+	return nil
+}
+
+func scanRevision(row rowScanner) (ds.Revision, error) {
+	var revision ds.Revision
+	var authorID uuid.NullUUID
+	var email sql.NullString
+
+	err := row.Scan(&revision.ID, &revision.EntityType, &revision.EntityID, &authorID, &email, &revision.Body, &revision.Diff, &revision.CreatedAt)
+	if err != nil {
+		return ds.Revision{}, err
+	}
+
+	if authorID.Valid {
+		revision.AuthorID = &authorID.UUID
+	}
+	revision.AuthorEmail = email.String
+	return revision, nil
+}
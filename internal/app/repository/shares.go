@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/ds"
+)
+
+type ShareRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewShareRepo(db *sqlx.DB) *ShareRepositoryImpl {
+	return &ShareRepositoryImpl{
+		db: db,
+	}
+}
+
+func (s ShareRepositoryImpl) CreateShare(ctx context.Context, share ds.ShareLink, tokenHash string) (ds.ShareLink, error) {
+	err := checkTravel(ctx, s.db, share.TravelID, ds.RoleOwner)
+	if err != nil {
+		return ds.ShareLink{}, err
+	}
+
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.ShareLink{}, err
+	}
+
+	share.ID = uuid.New()
+
+	err = s.db.QueryRowContext(ctx, "INSERT INTO travel_shares (id, travel_id, token_hash, hide_expenses, strip_location, created_by, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at",
+		share.ID, share.TravelID, tokenHash, share.HideExpenses, share.StripLocation, userID, share.ExpiresAt).Scan(&share.CreatedAt)
+	if err != nil {
+		return ds.ShareLink{}, fmt.Errorf("[db.QueryRowContext]: %w", err)
+	}
+	return share, nil
+}
+
+// GetShares - действующие ссылки путешествия
+func (s ShareRepositoryImpl) GetShares(ctx context.Context, travelUUID uuid.UUID) ([]ds.ShareLink, error) {
+	err := checkTravel(ctx, s.db, travelUUID, ds.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := []ds.ShareLink{}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, travel_id, hide_expenses, strip_location, created_at, expires_at FROM travel_shares WHERE travel_id = $1 AND "+shareActive+" ORDER BY created_at", travelUUID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func (s ShareRepositoryImpl) RevokeShare(ctx context.Context, travelUUID, shareUUID uuid.UUID) error {
+	err := checkTravel(ctx, s.db, travelUUID, ds.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, "UPDATE travel_shares SET revoked_at = now() WHERE id = $1 AND travel_id = $2 AND revoked_at IS NULL", shareUUID, travelUUID)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return affected(result)
+}
+
+// ResolveShare находит действующую ссылку по хешу токена. Вызывается без аутентификации
+func (s ShareRepositoryImpl) ResolveShare(ctx context.Context, tokenHash string) (ds.ShareLink, error) {
+	share, err := scanShare(s.db.QueryRowContext(ctx, "SELECT id, travel_id, hide_expenses, strip_location, created_at, expires_at FROM travel_shares WHERE token_hash = $1 AND "+shareActive, tokenHash))
+	if err != nil {
+		return ds.ShareLink{}, fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+	return share, nil
+}
+
+func scanShare(row rowScanner) (ds.ShareLink, error) {
+	var share ds.ShareLink
+	var expiresAt sql.NullTime
+
+	err := row.Scan(&share.ID, &share.TravelID, &share.HideExpenses, &share.StripLocation, &share.CreatedAt, &expiresAt)
+	if err != nil {
+		return ds.ShareLink{}, err
+	}
+
+	if expiresAt.Valid {
+		share.ExpiresAt = &expiresAt.Time
+	}
+	return share, nil
+}
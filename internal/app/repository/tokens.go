@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/ds"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrTokenReused - предъявлен уже заменённый refresh-токен, вся цепочка отозвана
+var ErrTokenReused = errors.New("refresh token reuse detected")
+
+type TokenRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewTokenRepo(db *sqlx.DB) *TokenRepositoryImpl {
+	return &TokenRepositoryImpl{
+		db: db,
+	}
+}
+
+func (t TokenRepositoryImpl) CreateRefreshToken(ctx context.Context, token ds.RefreshToken) error {
+	_, err := t.db.ExecContext(ctx, "INSERT INTO refresh_tokens (id, token_hash, user_id, family_id, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		token.ID, token.TokenHash, token.UserID, token.FamilyID, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return nil
+}
+
+// RotateRefreshToken отзывает предъявленный токен и выдаёт вместо него next из той же цепочки.
+// next.UserID и next.FamilyID заполняются из старого токена
+func (t TokenRepositoryImpl) RotateRefreshToken(ctx context.Context, oldHash string, next ds.RefreshToken) (ds.User, error) {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+
+	err = tx.QueryRowContext(ctx, "SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE", oldHash).Scan(
+		&id, &next.UserID, &next.FamilyID, &expiresAt, &revokedAt,
+	)
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[tx.QueryRowContext]: %w", notFound(err))
+	}
+
+	if revokedAt.Valid {
+		_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL", next.FamilyID)
+		if err != nil {
+			return ds.User{}, fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return ds.User{}, fmt.Errorf("[tx.Commit]: %w", err)
+		}
+		return ds.User{}, ErrTokenReused
+	}
+
+	if time.Now().After(expiresAt) {
+		return ds.User{}, ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now(), replaced_by = $1 WHERE id = $2", next.ID, id)
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO refresh_tokens (id, token_hash, user_id, family_id, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		next.ID, next.TokenHash, next.UserID, next.FamilyID, next.ExpiresAt)
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	var user ds.User
+	err = tx.QueryRowContext(ctx, "SELECT id, email, name, password_hash, created_at FROM users WHERE id = $1", next.UserID).Scan(
+		&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt,
+	)
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[tx.QueryRowContext]: %w", notFound(err))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return user, nil
+}
+
+// RevokeRefreshToken отзывает всю цепочку, к которой относится токен
+func (t TokenRepositoryImpl) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := t.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE revoked_at IS NULL AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)", tokenHash)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"lts/internal/app/ds"
+)
+
+func TestRotateRefreshToken(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	user, err := NewUserRepo(db).CreateUser(ctx, ds.User{Email: "user@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	tokens := NewTokenRepo(db)
+	expiresAt := time.Now().Add(time.Hour)
+	family := uuid.New()
+	next := func(hash string) ds.RefreshToken {
+		return ds.RefreshToken{ID: uuid.New(), TokenHash: hash, ExpiresAt: expiresAt}
+	}
+
+	err = tokens.CreateRefreshToken(ctx, ds.RefreshToken{ID: uuid.New(), TokenHash: "first", UserID: user.ID, FamilyID: family, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	other := ds.RefreshToken{ID: uuid.New(), TokenHash: "other", UserID: user.ID, FamilyID: uuid.New(), ExpiresAt: expiresAt}
+	err = tokens.CreateRefreshToken(ctx, other)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+
+	got, err := tokens.RotateRefreshToken(ctx, "first", next("second"))
+	if err != nil {
+		t.Fatalf("RotateRefreshToken(first) error = %v", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("RotateRefreshToken(first) user = %s, want %s", got.ID, user.ID)
+	}
+
+	// Повторное предъявление заменённого токена отзывает всю цепочку
+	_, err = tokens.RotateRefreshToken(ctx, "first", next("stolen"))
+	if !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("RotateRefreshToken(first) again error = %v, want %v", err, ErrTokenReused)
+	}
+	_, err = tokens.RotateRefreshToken(ctx, "second", next("third"))
+	if !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("RotateRefreshToken(second) after reuse error = %v, want %v", err, ErrTokenReused)
+	}
+
+	var live int
+	err = db.Get(&live, "SELECT count(*) FROM refresh_tokens WHERE family_id = $1 AND revoked_at IS NULL", family)
+	if err != nil {
+		t.Fatalf("count live tokens: %v", err)
+	}
+	if live != 0 {
+		t.Fatalf("%d tokens of the reused family are still live", live)
+	}
+
+	// Другие входы пользователя не затронуты
+	_, err = tokens.RotateRefreshToken(ctx, "other", next("other-next"))
+	if err != nil {
+		t.Fatalf("RotateRefreshToken(other) error = %v", err)
+	}
+}
+
+func TestRotateRefreshTokenRejected(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	user, err := NewUserRepo(db).CreateUser(ctx, ds.User{Email: "user@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	tokens := NewTokenRepo(db)
+	err = tokens.CreateRefreshToken(ctx, ds.RefreshToken{ID: uuid.New(), TokenHash: "expired", UserID: user.ID, FamilyID: uuid.New(), ExpiresAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"unknown", "unknown"},
+		{"expired", "expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tokens.RotateRefreshToken(ctx, tt.hash, ds.RefreshToken{ID: uuid.New(), TokenHash: tt.name + "-next", ExpiresAt: time.Now().Add(time.Hour)})
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("RotateRefreshToken() error = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	user, err := NewUserRepo(db).CreateUser(ctx, ds.User{Email: "user@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	tokens := NewTokenRepo(db)
+	err = tokens.CreateRefreshToken(ctx, ds.RefreshToken{ID: uuid.New(), TokenHash: "first", UserID: user.ID, FamilyID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	_, err = tokens.RotateRefreshToken(ctx, "first", ds.RefreshToken{ID: uuid.New(), TokenHash: "second", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() error = %v", err)
+	}
+
+	err = tokens.RevokeRefreshToken(ctx, "first")
+	if err != nil {
+		t.Fatalf("RevokeRefreshToken() error = %v", err)
+	}
+
+	// Выход по старому токену закрывает и выданный вместо него
+	_, err = tokens.RotateRefreshToken(ctx, "second", ds.RefreshToken{ID: uuid.New(), TokenHash: "third", ExpiresAt: time.Now().Add(time.Hour)})
+	if !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("RotateRefreshToken() after logout error = %v, want %v", err, ErrTokenReused)
+	}
+}
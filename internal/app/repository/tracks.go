@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lts/internal/app/ds"
+)
+
+type TrackRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewTrackRepo(db *sqlx.DB) *TrackRepositoryImpl {
+	return &TrackRepositoryImpl{
+		db: db,
+	}
+}
+
+func (t TrackRepositoryImpl) CreateTrack(ctx context.Context, track ds.Track) (ds.Track, error) {
+	err := checkTravel(ctx, t.db, track.TravelID, ds.RoleEditor)
+	if err != nil {
+		return ds.Track{}, err
+	}
+
+	track.ID = uuid.New()
+
+	err = audited(ctx, t.db, auditEntry{Action: ds.AuditCreate, Entity: ds.AuditTrack, EntityID: track.ID}, func(tx *sqlx.Tx) error {
+		return insertTrack(ctx, tx, track)
+	})
+	if err != nil {
+		return ds.Track{}, err
+	}
+	return track, nil
+}
+
+func insertTrack(ctx context.Context, tx *sqlx.Tx, track ds.Track) error {
+	segments, err := json.Marshal(track.Segments)
+	if err != nil {
+		return fmt.Errorf("[json.Marshal]: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO tracks (id, travel_id, name, segments, distance, elevation_gain, started_at, finished_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		track.ID, track.TravelID, track.Name, segments, track.Distance, track.ElevationGain, track.StartedAt, track.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+	return nil
+}
+
+func (t TrackRepositoryImpl) GetTracks(ctx context.Context, travelUUID uuid.UUID) ([]ds.Track, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := []ds.Track{}
+
+	rows, err := t.db.QueryContext(ctx, "SELECT id, travel_id, name, segments, distance, elevation_gain, started_at, finished_at FROM tracks WHERE travel_id = $1 AND travel_id IN ("+travelScope(2, ds.RoleViewer)+") ORDER BY started_at NULLS LAST", travelUUID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var track ds.Track
+		var segments []byte
+
+		err := rows.Scan(&track.ID, &track.TravelID, &track.Name, &segments, &track.Distance, &track.ElevationGain, &track.StartedAt, &track.FinishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+
+		err = json.Unmarshal(segments, &track.Segments)
+		if err != nil {
+			return nil, fmt.Errorf("[json.Unmarshal]: %w", err)
+		}
+
+		tracks = append(tracks, track)
+	}
+
+	return tracks, rows.Err()
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"lts/internal/app/ds"
+	"time"
+)
+
+type TrashRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewTrashRepo(db *sqlx.DB) *TrashRepositoryImpl {
+	return &TrashRepositoryImpl{
+		db: db,
+	}
+}
+
+// GetTrashedTravels - путешествия в корзине, которыми владеет пользователь
+func (t TrashRepositoryImpl) GetTrashedTravels(ctx context.Context) ([]ds.TrashItem, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.selectTrash(ctx, "SELECT id, id, coalesce(name, ''), deleted_at, deleted_by FROM travel WHERE deleted_at IS NOT NULL AND id IN ("+memberScope(1, ds.RoleOwner)+") ORDER BY deleted_at DESC", userID)
+}
+
+// GetTrashedPlaces - места путешествия в корзине
+func (t TrashRepositoryImpl) GetTrashedPlaces(ctx context.Context, travelUUID uuid.UUID) ([]ds.TrashItem, error) {
+	err := checkTravel(ctx, t.db, travelUUID, ds.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.selectTrash(ctx, "SELECT p.id, t.id, coalesce(p.name, ''), p.deleted_at, p.deleted_by FROM places p JOIN travel t ON p.id = ANY(t.places) WHERE t.id = $1 AND p.deleted_at IS NOT NULL ORDER BY p.deleted_at DESC", travelUUID)
+}
+
+func (t TrashRepositoryImpl) RestoreTravel(ctx context.Context, id uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return audited(ctx, t.db, auditEntry{Action: ds.AuditRestore, Entity: ds.AuditTravel, EntityID: id}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE travel SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND id IN ("+memberScope(2, ds.RoleOwner)+")", id, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
+}
+
+// RestorePlace восстанавливает место, если его путешествие не в корзине
+func (t TrashRepositoryImpl) RestorePlace(ctx context.Context, travelUUID, placeUUID uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return audited(ctx, t.db, auditEntry{Action: ds.AuditRestore, Entity: ds.AuditPlace, EntityID: placeUUID}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE places SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND id IN (SELECT unnest(places) FROM travel WHERE id = $2 AND id IN ("+travelScope(3, ds.RoleEditor)+"))", placeUUID, travelUUID, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
+}
+
+// PurgeTravels окончательно удаляет путешествия, попавшие в корзину раньше before, вместе с местами
+// и расходами, и возвращает их id, чтобы удалить файлы
+func (t TrashRepositoryImpl) PurgeTravels(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	var travels []uuid.UUID
+
+	err = tx.SelectContext(ctx, &travels, "SELECT id FROM travel WHERE deleted_at < $1 FOR UPDATE", before)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.SelectContext]: %w", err)
+	}
+	if len(travels) == 0 {
+		return nil, nil
+	}
+
+	ids := uuidArray(travels)
+
+	// Перемещения, участники и треки удаляются каскадно вместе с путешествием,
+	// а расходы с участием участников не дают их удалить
+	_, err = tx.ExecContext(ctx, "DELETE FROM expenses WHERE id IN (SELECT expenses FROM legs WHERE travel_id = ANY($1::uuid[]) UNION SELECT p.expenses FROM places p JOIN travel t ON p.id = ANY(t.places) WHERE t.id = ANY($1::uuid[])) "+
+		"OR payer IN (SELECT id FROM participants WHERE travel_id = ANY($1::uuid[])) "+
+		"OR id IN (SELECT s.expense_id FROM expense_shares s JOIN participants p ON p.id = s.participant_id WHERE p.travel_id = ANY($1::uuid[]))", ids)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM places WHERE id IN (SELECT unnest(places) FROM travel WHERE id = ANY($1::uuid[]))", ids)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM travel WHERE id = ANY($1::uuid[])", ids)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return travels, nil
+}
+
+// PurgePlaces окончательно удаляет места, попавшие в корзину раньше before, вместе с их расходами
+// и перемещениями, и возвращает их (с id путешествия), чтобы удалить файлы
+func (t TrashRepositoryImpl) PurgePlaces(ctx context.Context, before time.Time) ([]ds.TrashItem, error) {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	var places []ds.TrashItem
+	var ids []uuid.UUID
+
+	rows, err := tx.QueryContext(ctx, "SELECT p.id, t.id FROM places p JOIN travel t ON p.id = ANY(t.places) WHERE p.deleted_at < $1 FOR UPDATE OF p", before)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.QueryContext]: %w", err)
+	}
+	for rows.Next() {
+		var place ds.TrashItem
+		if err := rows.Scan(&place.ID, &place.TravelID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		places = append(places, place)
+		ids = append(ids, place.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("[rows.Err]: %w", err)
+	}
+	if len(places) == 0 {
+		return nil, nil
+	}
+
+	array := uuidArray(ids)
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM expenses WHERE id IN (SELECT expenses FROM places WHERE id = ANY($1::uuid[]) UNION SELECT expenses FROM legs WHERE from_place = ANY($1::uuid[]) OR to_place = ANY($1::uuid[]))", array)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE travel SET places = ARRAY(SELECT u.id FROM unnest(places) WITH ORDINALITY AS u(id, n) WHERE u.id <> ALL($1::uuid[]) ORDER BY u.n) WHERE places && $1::uuid[]", array)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	// Перемещения из этих мест и в них удаляются каскадно
+	_, err = tx.ExecContext(ctx, "DELETE FROM places WHERE id = ANY($1::uuid[])", array)
+	if err != nil {
+		return nil, fmt.Errorf("[tx.ExecContext]: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return places, nil
+}
+
+func (t TrashRepositoryImpl) selectTrash(ctx context.Context, query string, args ...any) ([]ds.TrashItem, error) {
+	items := []ds.TrashItem{}
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[db.QueryContext]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ds.TrashItem
+		var deletedBy uuid.NullUUID
+
+		if err := rows.Scan(&item.ID, &item.TravelID, &item.Name, &item.DeletedAt, &deletedBy); err != nil {
+			return nil, fmt.Errorf("[rows.Scan]: %w", err)
+		}
+		if deletedBy.Valid {
+			item.DeletedBy = &deletedBy.UUID
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func uuidArray(ids []uuid.UUID) pq.StringArray {
+	array := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		array[i] = id.String()
+	}
+	return array
+}
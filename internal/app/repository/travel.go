@@ -8,71 +8,219 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"lts/internal/app/ds"
+	"time"
 )
 
 type TravelRepositoryImpl struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	places *PlaceRepositoryImpl
 }
 
 func NewTravelRepo(db *sqlx.DB) *TravelRepositoryImpl {
 	return &TravelRepositoryImpl{
-		db: db,
+		db:     db,
+		places: NewPlaceRepositoryImpl(db),
 	}
 }
 
 func (t TravelRepositoryImpl) CreateTravel(ctx context.Context, travel ds.Travel) (ds.Travel, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Travel{}, err
+	}
+
 	travel.ID = uuid.New()
 
-	_, err := t.db.ExecContext(ctx, "INSERT INTO travel VALUES ($1, $2, $3, $4, $5)", travel.ID, travel.Name, travel.Description, travel.DateStart.Time, travel.DateEnd.Time)
+	err = audited(ctx, t.db, auditEntry{Action: ds.AuditCreate, Entity: ds.AuditTravel, EntityID: travel.ID}, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO travel (id, name, description, date_start, date_end, owner_id) VALUES ($1, $2, $3, $4, $5, $6)", travel.ID, travel.Name, travel.Description, travel.DateStart.Time, travel.DateEnd.Time, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO travel_members (travel_id, user_id, role) VALUES ($1, $2, $3)", travel.ID, userID, ds.RoleOwner)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		return addRevision(ctx, tx, ds.RevisionTravel, travel.ID, travel.Description)
+	})
 	if err != nil {
-		return ds.Travel{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Travel{}, err
 	}
 	return travel, nil
 }
 
 func (t TravelRepositoryImpl) UpdateTravel(ctx context.Context, id uuid.UUID, travel ds.Travel) error {
-	_, err := t.db.ExecContext(ctx, "UPDATE travel SET (name, description, date_start, date_end) = ($1, $2, $3, $4) WHERE id = $5", travel.Name, travel.Description, travel.DateStart.Time, travel.DateEnd.Time, id)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
 
-	return nil
+	return audited(ctx, t.db, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditTravel, EntityID: id}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE travel SET (name, description, date_start, date_end) = ($1, $2, $3, $4) WHERE id = $5 AND id IN ("+travelScope(6, ds.RoleEditor)+")", travel.Name, travel.Description, travel.DateStart.Time, travel.DateEnd.Time, id, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		err = affected(result)
+		if err != nil {
+			return err
+		}
+
+		return addRevision(ctx, tx, ds.RevisionTravel, id, travel.Description)
+	})
 }
 
+// DeleteTravel перемещает путешествие в корзину. Места, расходы и файлы остаются до очистки корзины
 func (t TravelRepositoryImpl) DeleteTravel(ctx context.Context, id uuid.UUID) error {
-	_, err := t.db.ExecContext(ctx, "DELETE FROM travel WHERE id = $1", id)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
 
-	return nil
+	return audited(ctx, t.db, auditEntry{Action: ds.AuditDelete, Entity: ds.AuditTravel, EntityID: id}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE travel SET deleted_at = now(), deleted_by = $2 WHERE id = $1 AND id IN ("+travelScope(2, ds.RoleOwner)+")", id, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		return affected(result)
+	})
 }
 
 func (t TravelRepositoryImpl) SetTravelPreview(ctx context.Context, path string, uuid uuid.UUID) error {
-	_, err := t.db.ExecContext(ctx, "UPDATE travel SET preview = $1 WHERE id = $2", path, uuid)
+	userID, err := currentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return err
 	}
-	return nil
+
+	return audited(ctx, t.db, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditMedia, Source: ds.AuditTravel, EntityID: uuid}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE travel SET preview = $1 WHERE id = $2 AND id IN ("+travelScope(3, ds.RoleEditor)+")", path, uuid, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
 }
 
 func (t TravelRepositoryImpl) AddPlace(ctx context.Context, travelUUID, placeUUID uuid.UUID) error {
-	_, err := t.db.ExecContext(ctx, "UPDATE travel SET places = array_append(places, $1) WHERE id = $2", placeUUID, travelUUID)
+	tx, err := t.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("[db.ExecContext]: %w", err)
+		return fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = t.AddPlaceTx(ctx, tx, travelUUID, placeUUID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("[tx.Commit]: %w", err)
 	}
 	return nil
 }
 
+// AddPlaceTx - AddPlace в транзакции вызывающего, например импорта GPX
+func (t TravelRepositoryImpl) AddPlaceTx(ctx context.Context, tx *sqlx.Tx, travelUUID, placeUUID uuid.UUID) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return auditedTx(ctx, tx, auditEntry{Action: ds.AuditCreate, Entity: ds.AuditPlace, EntityID: placeUUID}, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE travel SET places = array_append(places, $1) WHERE id = $2 AND id IN ("+travelScope(3, ds.RoleEditor)+")", placeUUID, travelUUID, userID)
+		if err != nil {
+			return fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+		return affected(result)
+	})
+}
+
+// ImportGPX сохраняет треки и места из GPX и расширяет даты путешествия до imported.DateStart и imported.DateEnd
+// в одной транзакции: при любой ошибке импорт откатывается целиком.
+// Места создаются так же, как через CreatePlace и AddPlace.
+// Меняются только даты, поэтому ревизия описания не создаётся
+func (t TravelRepositoryImpl) ImportGPX(ctx context.Context, travelUUID uuid.UUID, imported ds.TrackImport) (ds.TrackImport, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.TrackImport{}, err
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ds.TrackImport{}, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	var dateStart, dateEnd time.Time
+	err = tx.QueryRowContext(ctx, "SELECT date_start, date_end FROM travel WHERE id = $1 AND id IN ("+travelScope(2, ds.RoleEditor)+") FOR UPDATE", travelUUID, userID).Scan(&dateStart, &dateEnd)
+	if err != nil {
+		return ds.TrackImport{}, fmt.Errorf("[tx.QueryRowContext]: %w", notFound(err))
+	}
+
+	for i := range imported.Tracks {
+		imported.Tracks[i].ID = uuid.New()
+		imported.Tracks[i].TravelID = travelUUID
+
+		err = auditedTx(ctx, tx, auditEntry{Action: ds.AuditCreate, Entity: ds.AuditTrack, EntityID: imported.Tracks[i].ID}, func(tx *sqlx.Tx) error {
+			return insertTrack(ctx, tx, imported.Tracks[i])
+		})
+		if err != nil {
+			return ds.TrackImport{}, err
+		}
+	}
+
+	for i := range imported.Places {
+		imported.Places[i], err = t.places.CreatePlaceTx(ctx, tx, imported.Places[i])
+		if err != nil {
+			return ds.TrackImport{}, err
+		}
+
+		err = t.AddPlaceTx(ctx, tx, travelUUID, imported.Places[i].ID)
+		if err != nil {
+			return ds.TrackImport{}, err
+		}
+	}
+
+	if !imported.DateStart.Equal(dateStart) || !imported.DateEnd.Equal(dateEnd) {
+		err = auditedTx(ctx, tx, auditEntry{Action: ds.AuditUpdate, Entity: ds.AuditTravel, EntityID: travelUUID}, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE travel SET (date_start, date_end) = ($1, $2) WHERE id = $3", imported.DateStart.Time, imported.DateEnd.Time, travelUUID)
+			if err != nil {
+				return fmt.Errorf("[tx.ExecContext]: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return ds.TrackImport{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return ds.TrackImport{}, fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return imported, nil
+}
+
+// livePlaces - места путешествия без удалённых в корзину, в прежнем порядке
+const livePlaces = "ARRAY(SELECT p.id FROM unnest(travel.places) WITH ORDINALITY AS u(id, n) JOIN places p ON p.id = u.id WHERE p.deleted_at IS NULL ORDER BY u.n)"
+
 func (t TravelRepositoryImpl) GetTravel(ctx context.Context, travelUUID uuid.UUID) (ds.Travel, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return ds.Travel{}, err
+	}
+
 	var travel ds.Travel
 	var placesBytes []byte
 	var preview sql.NullString
-	err := t.db.QueryRowContext(ctx, "SELECT id, name, description, date_start, date_end, places, preview FROM travel WHERE id = $1", travelUUID).Scan(
+	err = t.db.QueryRowContext(ctx, "SELECT id, name, description, date_start, date_end, "+livePlaces+", preview FROM travel WHERE id = $1 AND id IN ("+travelScope(2, ds.RoleViewer)+")", travelUUID, userID).Scan(
 		&travel.ID, &travel.Name, &travel.Description, &travel.DateStart.Time, &travel.DateEnd.Time, &placesBytes, &preview,
 	)
 	if err != nil {
-		return ds.Travel{}, fmt.Errorf("[db.ExecContext]: %w", err)
+		return ds.Travel{}, fmt.Errorf("[db.ExecContext]: %w", notFound(err))
 	}
 
 	travel.Preview = preview.String
@@ -102,16 +250,23 @@ func (t TravelRepositoryImpl) GetTravel(ctx context.Context, travelUUID uuid.UUI
 
 func (t TravelRepositoryImpl) GetAllTravels(ctx context.Context) ([]ds.TravelCard, error) {
 	var travels []ds.TravelCard
-	rows, err := t.db.Query("SELECT id, name, date_start, date_end, preview FROM travel")
+
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return travels, err
+	}
+
+	rows, err := t.db.QueryContext(ctx, "SELECT t.id, t.name, t.date_start, t.date_end, t.preview, m.role FROM travel t JOIN travel_members m ON m.travel_id = t.id WHERE m.user_id = $1 AND t.deleted_at IS NULL", userID)
 	if err != nil {
 		return travels, fmt.Errorf("[db.Query]: %w", err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var travel ds.TravelCard
 		var preview sql.NullString
 
-		if err := rows.Scan(&travel.ID, &travel.Name, &travel.DateStart.Time, &travel.DateEnd.Time, &preview); err != nil {
+		if err := rows.Scan(&travel.ID, &travel.Name, &travel.DateStart.Time, &travel.DateEnd.Time, &preview, &travel.Role); err != nil {
 			return travels, fmt.Errorf("[rows.Scan]: %w", err)
 		}
 		travel.Preview = preview.String
@@ -119,5 +274,82 @@ func (t TravelRepositoryImpl) GetAllTravels(ctx context.Context) ([]ds.TravelCar
 		travels = append(travels, travel)
 	}
 
-	return travels, nil
+	return travels, rows.Err()
+}
+
+// GetStorageUsage - сколько байт занимают файлы всех путешествий создателя путешествия travelUUID.
+// По этой сумме считается квота на файлы
+func (t TravelRepositoryImpl) GetStorageUsage(ctx context.Context, travelUUID uuid.UUID) (int64, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var used int64
+
+	err = t.db.GetContext(ctx, &used, "SELECT (SELECT coalesce(sum(f.size), 0) FROM storage_files f JOIN travel o ON o.id = f.travel_id WHERE o.owner_id = t.owner_id) "+
+		"FROM travel t WHERE t.id = $1 AND t.id IN ("+travelScope(2, ds.RoleViewer)+")", travelUUID, userID)
+	if err != nil {
+		return 0, notFound(fmt.Errorf("[db.GetContext]: %w", err))
+	}
+	return used, nil
+}
+
+// AddFile учитывает записанный файл в квоте. Перезаписанный файл заменяет прежний размер
+func (t TravelRepositoryImpl) AddFile(ctx context.Context, file ds.StoredFile) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := t.db.ExecContext(ctx, "INSERT INTO storage_files (path, travel_id, place_id, size) SELECT $1::text, $2::uuid, $3::uuid, $4::bigint WHERE $2 IN ("+travelScope(5, ds.RoleEditor)+") "+
+		"ON CONFLICT (path) DO UPDATE SET size = excluded.size", file.Path, file.TravelID, nullUUID(file.PlaceID), file.Size, userID)
+	if err != nil {
+		return fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+	return affected(result)
+}
+
+// HasFiles - учтён ли в квоте хотя бы один файл
+func (t TravelRepositoryImpl) HasFiles(ctx context.Context) (bool, error) {
+	var exists bool
+
+	err := t.db.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM storage_files)")
+	if err != nil {
+		return false, fmt.Errorf("[db.GetContext]: %w", err)
+	}
+	return exists, nil
+}
+
+// IndexFiles учитывает файлы, записанные до появления учёта. Файлы удалённых путешествий пропускаются,
+// файлы удалённых мест считаются файлами путешествия. Возвращает, сколько файлов добавлено
+func (t TravelRepositoryImpl) IndexFiles(ctx context.Context, files []ds.StoredFile) (int64, error) {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("[db.BeginTxx]: %w", err)
+	}
+	defer tx.Rollback()
+
+	var added int64
+
+	for _, file := range files {
+		result, err := tx.ExecContext(ctx, "INSERT INTO storage_files (path, travel_id, place_id, size) "+
+			"SELECT $1::text, id, (SELECT id FROM places WHERE id = $3), $4::bigint FROM travel WHERE id = $2 ON CONFLICT (path) DO NOTHING",
+			file.Path, file.TravelID, nullUUID(file.PlaceID), file.Size)
+		if err != nil {
+			return 0, fmt.Errorf("[tx.ExecContext]: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("[result.RowsAffected]: %w", err)
+		}
+		added += rows
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("[tx.Commit]: %w", err)
+	}
+	return added, nil
 }
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lts/internal/app/auth"
+	"lts/internal/app/ds"
+)
+
+func TestImportGPX(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	users := NewUserRepo(db)
+	owner, err := users.CreateUser(ctx, ds.User{Email: "owner@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	viewer, err := users.CreateUser(ctx, ds.User{Email: "viewer@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	ownerCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: owner.ID})
+	viewerCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: viewer.ID})
+
+	day := func(d int) ds.DateOnlyTime {
+		return ds.DateOnlyTime{Time: time.Date(2024, 7, d, 0, 0, 0, 0, time.UTC)}
+	}
+
+	travels := NewTravelRepo(db)
+	travel, err := travels.CreateTravel(ownerCtx, ds.Travel{Name: "Summer", DateStart: day(10), DateEnd: day(12)})
+	if err != nil {
+		t.Fatalf("CreateTravel() error = %v", err)
+	}
+	_, err = db.Exec("INSERT INTO travel_members (travel_id, user_id, role) VALUES ($1, $2, $3)", travel.ID, viewer.ID, ds.RoleViewer)
+	if err != nil {
+		t.Fatalf("insert member: %v", err)
+	}
+
+	imported := func(countryCode string) ds.TrackImport {
+		return ds.TrackImport{
+			Places: []ds.Place{
+				{Name: "Start", Date: day(9)},
+				{Name: "Finish", Date: day(13), CountryCode: countryCode},
+			},
+			Tracks:    []ds.Track{{Name: "Day 1"}},
+			DateStart: day(9),
+			DateEnd:   day(13),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		data   ds.TrackImport
+		fails  bool
+		err    error
+		places int
+	}{
+		{"viewer cannot import", viewerCtx, imported("RU"), true, ErrNotFound, 0},
+		{"failed place rolls back the whole import", ownerCtx, imported("RUS"), true, nil, 0},
+		{"editor imports", ownerCtx, imported("RU"), false, nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := travels.ImportGPX(tt.ctx, travel.ID, tt.data)
+			if tt.fails != (err != nil) || (tt.err != nil && !errors.Is(err, tt.err)) {
+				t.Fatalf("ImportGPX() error = %v, want failure %t (%v)", err, tt.fails, tt.err)
+			}
+
+			got, err := travels.GetTravel(ownerCtx, travel.ID)
+			if err != nil {
+				t.Fatalf("GetTravel() error = %v", err)
+			}
+			if len(got.Places) != tt.places {
+				t.Fatalf("travel has %d places, want %d", len(got.Places), tt.places)
+			}
+
+			var tracks int
+			err = db.Get(&tracks, "SELECT count(*) FROM tracks WHERE travel_id = $1", travel.ID)
+			if err != nil {
+				t.Fatalf("count tracks: %v", err)
+			}
+			if want := min(tt.places, 1); tracks != want {
+				t.Fatalf("travel has %d tracks, want %d", tracks, want)
+			}
+
+			wantStart := day(10)
+			if tt.places > 0 {
+				wantStart = day(9)
+			}
+			if !got.DateStart.Equal(wantStart.Time) {
+				t.Fatalf("travel starts %v, want %v", got.DateStart.Time, wantStart.Time)
+			}
+		})
+	}
+}
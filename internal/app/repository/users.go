@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"lts/internal/app/ds"
+	"strings"
+)
+
+type UserRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewUserRepo(db *sqlx.DB) *UserRepositoryImpl {
+	return &UserRepositoryImpl{
+		db: db,
+	}
+}
+
+func (u UserRepositoryImpl) CreateUser(ctx context.Context, user ds.User) (ds.User, error) {
+	user.ID = uuid.New()
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+
+	err := u.db.QueryRowContext(ctx, "INSERT INTO users (id, email, name, password_hash) VALUES ($1, $2, $3, $4) RETURNING created_at",
+		user.ID, user.Email, user.Name, user.PasswordHash).Scan(&user.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ds.User{}, ErrConflict
+		}
+		return ds.User{}, fmt.Errorf("[db.QueryRowContext]: %w", err)
+	}
+	return user, nil
+}
+
+func (u UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (ds.User, error) {
+	var user ds.User
+	err := u.db.QueryRowContext(ctx, "SELECT id, email, name, password_hash, created_at FROM users WHERE email = $1", strings.ToLower(strings.TrimSpace(email))).Scan(
+		&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt,
+	)
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+	return user, nil
+}
+
+func (u UserRepositoryImpl) GetUser(ctx context.Context, id uuid.UUID) (ds.User, error) {
+	var user ds.User
+	err := u.db.QueryRowContext(ctx, "SELECT id, email, name, password_hash, created_at FROM users WHERE id = $1", id).Scan(
+		&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt,
+	)
+	if err != nil {
+		return ds.User{}, fmt.Errorf("[db.QueryRowContext]: %w", notFound(err))
+	}
+	return user, nil
+}
+
+// AdoptOrphanTravels делает пользователя с email владельцем путешествий, созданных до появления
+// пользователей: у них нет ни владельца, ни участников, поэтому их никто не видит.
+// Возвращает число назначенных путешествий, 0 - если такого пользователя ещё нет
+func (u UserRepositoryImpl) AdoptOrphanTravels(ctx context.Context, email string) (int64, error) {
+	result, err := u.db.ExecContext(ctx, "WITH adopted AS ("+
+		"UPDATE travel t SET owner_id = u.id FROM users u WHERE u.email = $1 AND t.owner_id IS NULL "+
+		"AND NOT EXISTS (SELECT 1 FROM travel_members m WHERE m.travel_id = t.id) RETURNING t.id, u.id AS user_id) "+
+		"INSERT INTO travel_members (travel_id, user_id, role) SELECT id, user_id, 'owner' FROM adopted", strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return 0, fmt.Errorf("[db.ExecContext]: %w", err)
+	}
+
+	adopted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("[result.RowsAffected]: %w", err)
+	}
+	return adopted, nil
+}
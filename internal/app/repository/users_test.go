@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"lts/internal/app/ds"
+)
+
+func TestAdoptOrphanTravels(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	users := NewUserRepo(db)
+	owner, err := users.CreateUser(ctx, ds.User{Email: "owner@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	other, err := users.CreateUser(ctx, ds.User{Email: "other@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	orphan, owned := uuid.New(), uuid.New()
+	_, err = db.Exec("INSERT INTO travel (id, name) VALUES ($1, 'orphan'), ($2, 'owned')", orphan, owned)
+	if err != nil {
+		t.Fatalf("insert travels: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO travel_members (travel_id, user_id, role) VALUES ($1, $2, 'owner')", owned, other.ID)
+	if err != nil {
+		t.Fatalf("insert member: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		email string
+		want  int64
+	}{
+		{"unknown user", "nobody@example.com", 0},
+		{"owner", " Owner@Example.com ", 1},
+		{"second run", "owner@example.com", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := users.AdoptOrphanTravels(ctx, tt.email)
+			if err != nil {
+				t.Fatalf("AdoptOrphanTravels() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("AdoptOrphanTravels() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	var members []struct {
+		TravelID uuid.UUID `db:"travel_id"`
+		UserID   uuid.UUID `db:"user_id"`
+		Role     ds.Role   `db:"role"`
+	}
+	err = db.Select(&members, "SELECT travel_id, user_id, role FROM travel_members WHERE user_id = $1", owner.ID)
+	if err != nil {
+		t.Fatalf("select members: %v", err)
+	}
+	if len(members) != 1 || members[0].TravelID != orphan || members[0].Role != ds.RoleOwner {
+		t.Fatalf("owner memberships = %+v, want owner of %s", members, orphan)
+	}
+}
@@ -0,0 +1,19 @@
+package requestid
+
+import "context"
+
+// Header - заголовок, в котором клиент или прокси передаёт id запроса
+const Header = "X-Request-ID"
+
+type key struct{}
+
+// With кладёт id запроса в контекст
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext достаёт id запроса из контекста, пустая строка - если его нет
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
@@ -0,0 +1,335 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/google/uuid"
+	"lts/internal/app/ds"
+)
+
+var (
+	ErrUnknownSplitType = errors.New("unknown split type")
+	ErrNoParticipants   = errors.New("expense has no participants to split between")
+	ErrNegativeShare    = errors.New("share value must not be negative")
+	ErrZeroShares       = errors.New("sum of shares must be positive")
+	ErrExactMismatch    = errors.New("sum of exact amounts does not match expense total")
+	ErrUnknownMember    = errors.New("participant does not belong to the travel")
+	ErrNegativeAmount   = errors.New("expense amounts must not be negative")
+	ErrDuplicateShare   = errors.New("participant appears in shares more than once")
+)
+
+// Validate проверяет суммы и правило разделения расхода без привязки к участникам путешествия
+func Validate(expense ds.Expense) error {
+	for _, amount := range []int{expense.Road, expense.Residence, expense.Food, expense.Entertainment, expense.Other} {
+		if amount < 0 {
+			return ErrNegativeAmount
+		}
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(expense.Shares))
+	for _, share := range expense.Shares {
+		if _, ok := seen[share.ParticipantID]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateShare, share.ParticipantID)
+		}
+		seen[share.ParticipantID] = struct{}{}
+	}
+
+	switch expense.SplitType {
+	case "", ds.SplitEqual:
+		return nil
+	case ds.SplitShares, ds.SplitExact:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSplitType, expense.SplitType)
+	}
+
+	if len(expense.Shares) == 0 {
+		return ErrNoParticipants
+	}
+
+	sum := 0
+	for _, share := range expense.Shares {
+		if share.Value < 0 {
+			return ErrNegativeShare
+		}
+		sum += share.Value
+	}
+
+	if expense.SplitType == ds.SplitShares && sum == 0 {
+		return ErrZeroShares
+	}
+	if expense.SplitType == ds.SplitExact && sum != expense.Total() {
+		return fmt.Errorf("%w: %d != %d", ErrExactMismatch, sum, expense.Total())
+	}
+
+	return nil
+}
+
+// CheckMembers проверяет, что плательщик и участники долей - участники путешествия
+func CheckMembers(expense ds.Expense, participants []ds.Participant) error {
+	members := make(map[uuid.UUID]struct{}, len(participants))
+	for _, p := range participants {
+		members[p.ID] = struct{}{}
+	}
+
+	if expense.Payer != uuid.Nil {
+		if _, ok := members[expense.Payer]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownMember, expense.Payer)
+		}
+	}
+	for _, share := range expense.Shares {
+		if _, ok := members[share.ParticipantID]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownMember, share.ParticipantID)
+		}
+	}
+	return nil
+}
+
+// Allocate возвращает, сколько из суммы расхода приходится на каждого участника.
+// Остаток от целочисленного деления раздаётся по одному участникам в порядке Shares,
+// так что сумма долей всегда равна expense.Total()
+func Allocate(expense ds.Expense, participants []ds.Participant) (map[uuid.UUID]int, error) {
+	err := Validate(expense)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[uuid.UUID]struct{}, len(participants))
+	for _, p := range participants {
+		members[p.ID] = struct{}{}
+	}
+
+	shares := expense.Shares
+	if len(shares) == 0 {
+		for _, p := range participants {
+			shares = append(shares, ds.ExpenseShare{ParticipantID: p.ID})
+		}
+	}
+	if len(shares) == 0 {
+		return nil, ErrNoParticipants
+	}
+
+	for _, share := range shares {
+		if _, ok := members[share.ParticipantID]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownMember, share.ParticipantID)
+		}
+	}
+
+	total := expense.Total()
+	result := make(map[uuid.UUID]int, len(shares))
+
+	switch expense.SplitType {
+	case ds.SplitExact:
+		for _, share := range shares {
+			result[share.ParticipantID] += share.Value
+		}
+	case ds.SplitShares:
+		weights := make([]int, len(shares))
+		for i, share := range shares {
+			weights[i] = share.Value
+		}
+		for i, amount := range distribute(total, weights) {
+			result[shares[i].ParticipantID] += amount
+		}
+	default:
+		weights := make([]int, len(shares))
+		for i := range weights {
+			weights[i] = 1
+		}
+		for i, amount := range distribute(total, weights) {
+			result[shares[i].ParticipantID] += amount
+		}
+	}
+
+	return result, nil
+}
+
+// distribute делит total пропорционально весам, раздавая остаток по порядку
+func distribute(total int, weights []int) []int {
+	sum := 0
+	for _, w := range weights {
+		sum += w
+	}
+
+	amounts := make([]int, len(weights))
+	rest := total
+	for i, w := range weights {
+		amounts[i] = total * w / sum
+		rest -= amounts[i]
+	}
+
+	for i := 0; rest > 0; i = (i + 1) % len(amounts) {
+		if weights[i] == 0 {
+			continue
+		}
+		amounts[i]++
+		rest--
+	}
+
+	return amounts
+}
+
+// Balances считает, сколько каждый участник заплатил и сколько должен.
+// Расходы без плательщика (созданные до появления участников) не учитываются
+func Balances(participants []ds.Participant, expenses []ds.Expense) ([]ds.Balance, error) {
+	index := make(map[uuid.UUID]int, len(participants))
+	balances := make([]ds.Balance, len(participants))
+	for i, p := range participants {
+		index[p.ID] = i
+		balances[i] = ds.Balance{ParticipantID: p.ID, Name: p.Name}
+	}
+
+	for _, expense := range expenses {
+		if expense.Payer == uuid.Nil {
+			continue
+		}
+
+		payer, ok := index[expense.Payer]
+		if !ok {
+			return nil, fmt.Errorf("expense %s: %w: %s", expense.ID, ErrUnknownMember, expense.Payer)
+		}
+
+		owed, err := Allocate(expense, participants)
+		if err != nil {
+			return nil, fmt.Errorf("expense %s: %w", expense.ID, err)
+		}
+
+		balances[payer].Paid += expense.Total()
+		for id, amount := range owed {
+			balances[index[id]].Owed += amount
+		}
+	}
+
+	for i := range balances {
+		balances[i].Net = balances[i].Paid - balances[i].Owed
+	}
+
+	return balances, nil
+}
+
+// maxExactParties - сколько участников с ненулевым балансом SettleUp разбивает на группы
+// точным перебором: он растёт как 2^n, а в путешествии обычно несколько человек
+const maxExactParties = 16
+
+type party struct {
+	id     uuid.UUID
+	amount int
+}
+
+// SettleUp подбирает минимальное число переводов, обнуляющих балансы. Участники с ненулевым
+// балансом делятся на как можно большее число групп с нулевой суммой: группа из k человек
+// рассчитывается за k-1 перевод, поэтому переводов получается n минус число групп.
+// Внутри группы самый крупный должник платит самому крупному кредитору.
+// Если таких участников больше maxExactParties, группы не ищутся и переводов не больше n-1
+func SettleUp(balances []ds.Balance) []ds.Transfer {
+	var parties []party
+	for _, b := range balances {
+		if b.Net != 0 {
+			parties = append(parties, party{id: b.ParticipantID, amount: b.Net})
+		}
+	}
+
+	transfers := []ds.Transfer{}
+	for _, group := range zeroSumGroups(parties) {
+		transfers = append(transfers, settleGroup(group)...)
+	}
+	return transfers
+}
+
+// zeroSumGroups разбивает участников на наибольшее число групп с нулевой суммой балансов.
+// best[mask] - сколько групп можно выделить из подмножества mask, если убирать участников
+// по одному: каждый раз, когда сумма оставшихся нулевая, замыкается очередная группа
+func zeroSumGroups(parties []party) [][]party {
+	n := len(parties)
+	if n == 0 {
+		return nil
+	}
+	if n > maxExactParties {
+		return [][]party{parties}
+	}
+
+	full := 1<<n - 1
+	sum := make([]int, full+1)
+	best := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sum[mask] = sum[mask&(mask-1)] + parties[low].amount
+
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				best[mask] = max(best[mask], best[mask&^(1<<i)])
+			}
+		}
+		if sum[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	var groups [][]party
+	var group []party
+	for mask := full; mask != 0; {
+		closed := 0
+		if sum[mask] == 0 {
+			closed = 1
+		}
+
+		for i := 0; i < n; i++ {
+			bit := 1 << i
+			if mask&bit != 0 && best[mask&^bit]+closed == best[mask] {
+				group = append(group, parties[i])
+				mask &^= bit
+				break
+			}
+		}
+
+		if sum[mask] == 0 {
+			groups = append(groups, group)
+			group = nil
+		}
+	}
+
+	return groups
+}
+
+// settleGroup рассчитывает группу с нулевой суммой балансов не больше чем за k-1 перевод:
+// на каждом шаге самый крупный должник платит самому крупному кредитору
+func settleGroup(parties []party) []ds.Transfer {
+	var debtors, creditors []party
+	for _, p := range parties {
+		switch {
+		case p.amount < 0:
+			debtors = append(debtors, party{id: p.id, amount: -p.amount})
+		case p.amount > 0:
+			creditors = append(creditors, p)
+		}
+	}
+
+	byAmount := func(parties []party) func(i, j int) bool {
+		return func(i, j int) bool { return parties[i].amount > parties[j].amount }
+	}
+	sort.SliceStable(debtors, byAmount(debtors))
+	sort.SliceStable(creditors, byAmount(creditors))
+
+	var transfers []ds.Transfer
+	for len(debtors) > 0 && len(creditors) > 0 {
+		amount := min(debtors[0].amount, creditors[0].amount)
+		transfers = append(transfers, ds.Transfer{From: debtors[0].id, To: creditors[0].id, Amount: amount})
+
+		debtors[0].amount -= amount
+		creditors[0].amount -= amount
+
+		if debtors[0].amount == 0 {
+			debtors = debtors[1:]
+		}
+		if creditors[0].amount == 0 {
+			creditors = creditors[1:]
+		}
+
+		sort.SliceStable(debtors, byAmount(debtors))
+		sort.SliceStable(creditors, byAmount(creditors))
+	}
+
+	return transfers
+}
@@ -0,0 +1,356 @@
+package settlement
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"lts/internal/app/ds"
+)
+
+var (
+	alice = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	bob   = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	carol = uuid.MustParse("00000000-0000-0000-0000-00000000000c")
+	dave  = uuid.MustParse("00000000-0000-0000-0000-00000000000d")
+	eve   = uuid.MustParse("00000000-0000-0000-0000-00000000000e")
+
+	participants = []ds.Participant{{ID: alice, Name: "Alice"}, {ID: bob, Name: "Bob"}, {ID: carol, Name: "Carol"}}
+)
+
+func shares(pairs ...any) []ds.ExpenseShare {
+	var result []ds.ExpenseShare
+	for i := 0; i < len(pairs); i += 2 {
+		result = append(result, ds.ExpenseShare{ParticipantID: pairs[i].(uuid.UUID), Value: pairs[i+1].(int)})
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		expense ds.Expense
+		err     error
+	}{
+		{"equal without shares", ds.Expense{Food: 100}, nil},
+		{"equal with shares", ds.Expense{Food: 100, SplitType: ds.SplitEqual, Shares: shares(alice, 0, bob, 0)}, nil},
+		{"negative amount", ds.Expense{Food: 100, Road: -1}, ErrNegativeAmount},
+		{"duplicate share", ds.Expense{Food: 100, Shares: shares(alice, 0, alice, 0)}, ErrDuplicateShare},
+		{"duplicate exact share", ds.Expense{Food: 100, SplitType: ds.SplitExact, Shares: shares(alice, 50, alice, 50)}, ErrDuplicateShare},
+		{"unknown split type", ds.Expense{Food: 100, SplitType: "percent"}, ErrUnknownSplitType},
+		{"shares without shares", ds.Expense{Food: 100, SplitType: ds.SplitShares}, ErrNoParticipants},
+		{"negative share", ds.Expense{Food: 100, SplitType: ds.SplitShares, Shares: shares(alice, -1, bob, 2)}, ErrNegativeShare},
+		{"zero shares", ds.Expense{Food: 100, SplitType: ds.SplitShares, Shares: shares(alice, 0, bob, 0)}, ErrZeroShares},
+		{"exact mismatch", ds.Expense{Food: 100, SplitType: ds.SplitExact, Shares: shares(alice, 30, bob, 60)}, ErrExactMismatch},
+		{"exact", ds.Expense{Food: 60, Road: 40, SplitType: ds.SplitExact, Shares: shares(alice, 30, bob, 70)}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.expense)
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("Validate() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckMembers(t *testing.T) {
+	tests := []struct {
+		name    string
+		expense ds.Expense
+		err     error
+	}{
+		{"no payer", ds.Expense{Food: 100}, nil},
+		{"members", ds.Expense{Food: 100, Payer: alice, Shares: shares(bob, 0, carol, 0)}, nil},
+		{"foreign payer", ds.Expense{Food: 100, Payer: dave}, ErrUnknownMember},
+		{"foreign share", ds.Expense{Food: 100, Payer: alice, Shares: shares(bob, 0, dave, 0)}, ErrUnknownMember},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckMembers(tt.expense, participants)
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("CheckMembers() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name         string
+		expense      ds.Expense
+		participants []ds.Participant
+		want         map[uuid.UUID]int
+		err          error
+	}{
+		{
+			name:         "equal between all participants, remainder in order",
+			expense:      ds.Expense{Food: 100},
+			participants: participants,
+			want:         map[uuid.UUID]int{alice: 34, bob: 33, carol: 33},
+		},
+		{
+			name:         "equal between shares",
+			expense:      ds.Expense{Food: 101, SplitType: ds.SplitEqual, Shares: shares(carol, 0, bob, 0)},
+			participants: participants,
+			want:         map[uuid.UUID]int{carol: 51, bob: 50},
+		},
+		{
+			name:         "shares by weight",
+			expense:      ds.Expense{Food: 100, SplitType: ds.SplitShares, Shares: shares(alice, 1, bob, 2)},
+			participants: participants,
+			want:         map[uuid.UUID]int{alice: 34, bob: 66},
+		},
+		{
+			name:         "remainder skips zero weight",
+			expense:      ds.Expense{Food: 10, SplitType: ds.SplitShares, Shares: shares(alice, 0, bob, 1, carol, 2)},
+			participants: participants,
+			want:         map[uuid.UUID]int{alice: 0, bob: 4, carol: 6},
+		},
+		{
+			name:         "exact",
+			expense:      ds.Expense{Food: 100, SplitType: ds.SplitExact, Shares: shares(alice, 30, bob, 70)},
+			participants: participants,
+			want:         map[uuid.UUID]int{alice: 30, bob: 70},
+		},
+		{
+			name:         "no participants",
+			expense:      ds.Expense{Food: 100},
+			participants: nil,
+			err:          ErrNoParticipants,
+		},
+		{
+			name:         "foreign share",
+			expense:      ds.Expense{Food: 100, Shares: shares(alice, 0, dave, 0)},
+			participants: participants,
+			err:          ErrUnknownMember,
+		},
+		{
+			name:         "invalid split",
+			expense:      ds.Expense{Food: 100, SplitType: ds.SplitExact, Shares: shares(alice, 10)},
+			participants: participants,
+			err:          ErrExactMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(tt.expense, tt.participants)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Allocate() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Allocate() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Allocate() = %v, want %v", got, tt.want)
+			}
+
+			sum := 0
+			for _, amount := range got {
+				sum += amount
+			}
+			if sum != tt.expense.Total() {
+				t.Fatalf("sum of allocations = %d, want %d", sum, tt.expense.Total())
+			}
+		})
+	}
+}
+
+func TestBalances(t *testing.T) {
+	tests := []struct {
+		name     string
+		expenses []ds.Expense
+		want     []ds.Balance
+		err      error
+	}{
+		{
+			name:     "rounding remainder",
+			expenses: []ds.Expense{{Food: 100, Payer: alice}},
+			want: []ds.Balance{
+				{ParticipantID: alice, Name: "Alice", Paid: 100, Owed: 34, Net: 66},
+				{ParticipantID: bob, Name: "Bob", Owed: 33, Net: -33},
+				{ParticipantID: carol, Name: "Carol", Owed: 33, Net: -33},
+			},
+		},
+		{
+			name: "expense without payer is skipped",
+			expenses: []ds.Expense{
+				{Food: 90},
+				{Road: 60, Payer: bob, SplitType: ds.SplitExact, Shares: shares(alice, 20, carol, 40)},
+			},
+			want: []ds.Balance{
+				{ParticipantID: alice, Name: "Alice", Owed: 20, Net: -20},
+				{ParticipantID: bob, Name: "Bob", Paid: 60, Net: 60},
+				{ParticipantID: carol, Name: "Carol", Owed: 40, Net: -40},
+			},
+		},
+		{
+			name:     "foreign payer",
+			expenses: []ds.Expense{{Food: 100, Payer: dave}},
+			err:      ErrUnknownMember,
+		},
+		{
+			name:     "invalid split",
+			expenses: []ds.Expense{{Food: 100, Payer: alice, SplitType: ds.SplitShares, Shares: shares(bob, 0)}},
+			err:      ErrZeroShares,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Balances(participants, tt.expenses)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Balances() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Balances() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Balances() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettleUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances []ds.Balance
+		want     []ds.Transfer
+	}{
+		{
+			name:     "settled",
+			balances: []ds.Balance{{ParticipantID: alice}, {ParticipantID: bob}},
+			want:     []ds.Transfer{},
+		},
+		{
+			name: "rounding remainder",
+			balances: []ds.Balance{
+				{ParticipantID: alice, Net: 66},
+				{ParticipantID: bob, Net: -33},
+				{ParticipantID: carol, Net: -33},
+			},
+			want: []ds.Transfer{
+				{From: bob, To: alice, Amount: 33},
+				{From: carol, To: alice, Amount: 33},
+			},
+		},
+		{
+			name: "largest debtor pays largest creditor",
+			balances: []ds.Balance{
+				{ParticipantID: alice, Net: 10},
+				{ParticipantID: bob, Net: 50},
+				{ParticipantID: carol, Net: -60},
+			},
+			want: []ds.Transfer{
+				{From: carol, To: bob, Amount: 50},
+				{From: carol, To: alice, Amount: 10},
+			},
+		},
+		{
+			name: "at most n-1 transfers",
+			balances: []ds.Balance{
+				{ParticipantID: alice, Net: 40},
+				{ParticipantID: bob, Net: -25},
+				{ParticipantID: carol, Net: -10},
+				{ParticipantID: dave, Net: -5},
+			},
+			want: []ds.Transfer{
+				{From: bob, To: alice, Amount: 25},
+				{From: carol, To: alice, Amount: 10},
+				{From: dave, To: alice, Amount: 5},
+			},
+		},
+		{
+			name: "zero-sum groups are settled separately",
+			balances: []ds.Balance{
+				{ParticipantID: alice, Net: 5},
+				{ParticipantID: bob, Net: -3},
+				{ParticipantID: carol, Net: -2},
+				{ParticipantID: dave, Net: 4},
+				{ParticipantID: eve, Net: -4},
+			},
+			want: []ds.Transfer{
+				{From: bob, To: alice, Amount: 3},
+				{From: carol, To: alice, Amount: 2},
+				{From: eve, To: dave, Amount: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SettleUp(tt.balances)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SettleUp() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettleUpAfterBalances(t *testing.T) {
+	expenses := []ds.Expense{
+		{Food: 100, Payer: alice},
+		{Residence: 77, Payer: bob, SplitType: ds.SplitShares, Shares: shares(alice, 1, bob, 1, carol, 1)},
+		{Road: 10, Payer: carol, SplitType: ds.SplitEqual, Shares: shares(alice, 0, carol, 0)},
+	}
+
+	balances, err := Balances(participants, expenses)
+	if err != nil {
+		t.Fatalf("Balances() error = %v", err)
+	}
+
+	net := make(map[uuid.UUID]int, len(balances))
+	for _, b := range balances {
+		net[b.ParticipantID] = b.Net
+	}
+	for _, transfer := range SettleUp(balances) {
+		if transfer.Amount <= 0 {
+			t.Fatalf("transfer %+v must be positive", transfer)
+		}
+		net[transfer.From] += transfer.Amount
+		net[transfer.To] -= transfer.Amount
+	}
+
+	for id, amount := range net {
+		if amount != 0 {
+			t.Fatalf("participant %s is left with %d after settle up", id, amount)
+		}
+	}
+}
+
+func TestSettleUpManyParticipants(t *testing.T) {
+	var balances []ds.Balance
+	for i := 1; i <= maxExactParties/2+1; i++ {
+		balances = append(balances, ds.Balance{ParticipantID: uuid.New(), Net: i}, ds.Balance{ParticipantID: uuid.New(), Net: -i})
+	}
+
+	net := make(map[uuid.UUID]int, len(balances))
+	for _, b := range balances {
+		net[b.ParticipantID] = b.Net
+	}
+
+	transfers := SettleUp(balances)
+	if len(transfers) > len(balances)-1 {
+		t.Fatalf("SettleUp() made %d transfers, want at most %d", len(transfers), len(balances)-1)
+	}
+	for _, transfer := range transfers {
+		net[transfer.From] += transfer.Amount
+		net[transfer.To] -= transfer.Amount
+	}
+	for id, amount := range net {
+		if amount != 0 {
+			t.Fatalf("participant %s is left with %d after settle up", id, amount)
+		}
+	}
+}
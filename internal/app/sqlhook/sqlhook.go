@@ -0,0 +1,120 @@
+package sqlhook
+
+import (
+	"context"
+	"database/sql/driver"
+	"runtime"
+	"strings"
+)
+
+// Observer вызывается перед каждым запросом к БД и возвращает функцию, которую вызывают по его завершении
+type Observer func(ctx context.Context, query string) func(err error)
+
+// Connector оборачивает соединения c так, что каждый QueryContext и ExecContext проходит через observe.
+// Остальные вызовы (транзакции, ping, проверка соединения) передаются драйверу без изменений
+func Connector(c driver.Connector, observe Observer) driver.Connector {
+	return connector{Connector: c, observe: observe}
+}
+
+type connector struct {
+	driver.Connector
+	observe Observer
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn{Conn: cn, observe: c.observe}, nil
+}
+
+type conn struct {
+	driver.Conn
+	observe Observer
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	done := c.observe(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	done(err)
+	return rows, err
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	done := c.observe(ctx, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	done(err)
+	return result, err
+}
+
+func (c conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() // драйверы без BeginTx
+}
+
+func (c conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c conn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// Caller ищет в стеке ближайший метод типа из пакета pkg (полный путь импорта)
+// и возвращает имя типа и метода. Функции пакета без получателя и замыкания
+// относятся к вызвавшему их методу. Если такого метода нет, возвращает пустые строки
+func Caller(pkg string) (string, string) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	prefix := pkg + "."
+	for {
+		frame, more := frames.Next()
+
+		if name, ok := strings.CutPrefix(frame.Function, prefix); ok {
+			// TravelRepositoryImpl.GetTravel, (*TravelRepositoryImpl).GetTravel, TravelRepositoryImpl.GetTravel.func1
+			parts := strings.Split(name, ".")
+			if len(parts) >= 2 && !strings.HasPrefix(parts[1], "func") {
+				return strings.Trim(parts[0], "(*)"), parts[1]
+			}
+		}
+
+		if !more {
+			return "", ""
+		}
+	}
+}
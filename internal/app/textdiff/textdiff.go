@@ -0,0 +1,182 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op - операция над строкой в разнице текстов
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// DefaultContext - строк контекста вокруг изменений, как у diff -u
+const DefaultContext = 3
+
+// maxCells ограничивает размер таблицы LCS. Для текстов больше этого
+// разница строится как удаление старого текста целиком и вставка нового
+const maxCells = 4_000_000
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines сравнивает тексты построчно по наибольшей общей подпоследовательности строк
+func Lines(a, b string) []Line {
+	old, cur := split(a), split(b)
+
+	// Общие начало и конец не участвуют в поиске подпоследовательности
+	prefix := 0
+	for prefix < len(old) && prefix < len(cur) && old[prefix] == cur[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(cur)-prefix && old[len(old)-1-suffix] == cur[len(cur)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(old)+len(cur))
+	for _, text := range old[:prefix] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	lines = append(lines, lcs(old[prefix:len(old)-suffix], cur[prefix:len(cur)-suffix])...)
+	for _, text := range old[len(old)-suffix:] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	return lines
+}
+
+func lcs(old, cur []string) []Line {
+	n, m := len(old), len(cur)
+
+	var lines []Line
+	if n*m > maxCells {
+		for _, text := range old {
+			lines = append(lines, Line{Op: Delete, Text: text})
+		}
+		for _, text := range cur {
+			lines = append(lines, Line{Op: Insert, Text: text})
+		}
+		return lines
+	}
+
+	// length[i][j] - длина общей подпоследовательности old[i:] и cur[j:]
+	length := make([][]int32, n+1)
+	for i := range length {
+		length[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if old[i] == cur[j] {
+				length[i][j] = length[i+1][j+1] + 1
+			} else {
+				length[i][j] = max(length[i+1][j], length[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case old[i] == cur[j]:
+			lines = append(lines, Line{Op: Equal, Text: old[i]})
+			i++
+			j++
+		case length[i+1][j] >= length[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: old[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: cur[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, Line{Op: Delete, Text: old[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, Line{Op: Insert, Text: cur[j]})
+	}
+	return lines
+}
+
+// Unified возвращает разницу в формате unified diff с context строками контекста вокруг изменений.
+// Для одинаковых текстов возвращается пустая строка
+func Unified(a, b string, context int) string {
+	lines := Lines(a, b)
+
+	// oldBefore[k] и newBefore[k] - сколько строк старого и нового текста идут до lines[k]
+	oldBefore := make([]int, len(lines)+1)
+	newBefore := make([]int, len(lines)+1)
+	for k, line := range lines {
+		oldBefore[k+1], newBefore[k+1] = oldBefore[k], newBefore[k]
+		if line.Op != Insert {
+			oldBefore[k+1]++
+		}
+		if line.Op != Delete {
+			newBefore[k+1]++
+		}
+	}
+
+	var out strings.Builder
+
+	for i := 0; i < len(lines); {
+		for i < len(lines) && lines[i].Op == Equal {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		// Изменения, между которыми не больше 2*context общих строк, попадают в один блок
+		j := i
+		for j < len(lines) {
+			if lines[j].Op != Equal {
+				j++
+				continue
+			}
+			k := j
+			for k < len(lines) && lines[k].Op == Equal {
+				k++
+			}
+			if k == len(lines) || k-j > 2*context {
+				break
+			}
+			j = k
+		}
+
+		start, end := max(i-context, 0), min(j+context, len(lines))
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldBefore[start], oldBefore[end]-oldBefore[start]), hunkRange(newBefore[start], newBefore[end]-newBefore[start]))
+		for _, line := range lines[start:end] {
+			out.WriteByte(byte(line.Op))
+			out.WriteString(line.Text)
+			out.WriteByte('\n')
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
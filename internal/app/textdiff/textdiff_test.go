@@ -0,0 +1,115 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{"empty", "", "", []Line{}},
+		{"equal", "a\nb\n", "a\nb\n", []Line{{Equal, "a"}, {Equal, "b"}}},
+		{"insert", "", "a\n", []Line{{Insert, "a"}}},
+		{"delete", "a\n", "", []Line{{Delete, "a"}}},
+		{
+			name: "replace inside common prefix and suffix",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: []Line{{Equal, "a"}, {Delete, "b"}, {Insert, "B"}, {Equal, "c"}},
+		},
+		{
+			name: "moved line",
+			a:    "a\nb\nc\n",
+			b:    "a\nc\nd\n",
+			want: []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "d"}},
+		},
+		{"trailing newline is ignored", "a", "a\n", []Line{{Equal, "a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	alphabet := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\no\np\n"
+
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", DefaultContext, ""},
+		{"both empty", "", "", DefaultContext, ""},
+		{"from empty", "", "a\nb\n", DefaultContext, "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"to empty", "a\nb\n", "", DefaultContext, "@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{
+			name:    "single line hunk",
+			a:       "a\n",
+			b:       "b\n",
+			context: DefaultContext,
+			want:    "@@ -1 +1 @@\n-a\n+b\n",
+		},
+		{
+			name:    "context around change",
+			a:       "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			b:       "a\nb\nc\nd\nE\nf\ng\nh\ni\nj\n",
+			context: DefaultContext,
+			want:    "@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n",
+		},
+		{
+			name:    "distant changes make separate hunks",
+			a:       alphabet,
+			b:       strings.Replace(strings.Replace(alphabet, "b\n", "B\n", 1), "o\n", "O\n", 1),
+			context: DefaultContext,
+			want:    "@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n@@ -12,5 +12,5 @@\n l\n m\n n\n-o\n+O\n p\n",
+		},
+		{
+			name:    "close changes share a hunk",
+			a:       "a\nb\nc\nd\ne\n",
+			b:       "A\nb\nc\nd\nE\n",
+			context: 2,
+			want:    "@@ -1,5 +1,5 @@\n-a\n+A\n b\n c\n d\n-e\n+E\n",
+		},
+		{
+			name:    "zero context",
+			a:       "a\nb\nc\n",
+			b:       "a\nc\nd\n",
+			context: 0,
+			want:    "@@ -2 +1,0 @@\n-b\n@@ -3,0 +3 @@\n+d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified(tt.a, tt.b, tt.context)
+			if got != tt.want {
+				t.Fatalf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesTooLarge(t *testing.T) {
+	a := strings.Repeat("a\n", 3000)
+	b := strings.Repeat("b\n", 3000)
+
+	lines := Lines("x\n"+a+"y\n", "x\n"+b+"y\n")
+	if len(lines) != 6002 {
+		t.Fatalf("len(Lines()) = %d, want 6002", len(lines))
+	}
+	if lines[1].Op != Delete || lines[3001].Op != Insert || lines[6001].Op != Equal {
+		t.Fatalf("large texts must be diffed as delete and insert between common lines")
+	}
+}
@@ -0,0 +1,93 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Reloader отдаёт серверу сертификат через tls.Config.GetCertificate и перечитывает его
+// с диска без перезапуска, например после продления в certbot
+type Reloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// NewReloader загружает сертификат и ключ. Ошибка здесь означает, что сервер нельзя запускать
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload перечитывает сертификат и ключ. При ошибке продолжает действовать прежний сертификат
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("[tls.LoadX509KeyPair]: %w", err)
+	}
+
+	r.cert.Store(&cert)
+	return nil
+}
+
+// GetCertificate - текущий сертификат, для tls.Config
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Watch перечитывает сертификат при изменениях файлов сертификата и ключа, пока не завершится ctx,
+// и сообщает результат каждой попытки в onReload. Следятся каталоги, а не файлы, чтобы не терять замену
+// файла переименованием и подмену симлинков в Kubernetes. Пока сертификат и ключ записаны
+// не оба, попытка завершается ошибкой и действует прежний сертификат
+func (r *Reloader) Watch(ctx context.Context, onReload func(error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("[fsnotify.NewWatcher]: %w", err)
+	}
+	defer watcher.Close()
+
+	for _, dir := range []string{filepath.Dir(r.certFile), filepath.Dir(r.keyFile)} {
+		err = watcher.Add(dir)
+		if err != nil {
+			return fmt.Errorf("[watcher.Add]: %w", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) || !r.affects(event.Name) {
+				continue
+			}
+			onReload(r.Reload())
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			onReload(fmt.Errorf("[fsnotify]: %w", err))
+		}
+	}
+}
+
+// affects сообщает, может ли изменение файла name затронуть сертификат: это сам сертификат, ключ
+// или служебный каталог ..data, через который Kubernetes атомарно подменяет содержимое Secret
+func (r *Reloader) affects(name string) bool {
+	name = filepath.Clean(name)
+	return name == filepath.Clean(r.certFile) || name == filepath.Clean(r.keyFile) || strings.HasPrefix(filepath.Base(name), "..")
+}
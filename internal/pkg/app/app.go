@@ -2,40 +2,85 @@ package app
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"log"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "lts/docs"
+	"lts/internal/app/auth"
 	"lts/internal/app/config"
+	"lts/internal/app/ds"
 	"lts/internal/app/handlers"
+	"lts/internal/app/metrics"
 	"lts/internal/app/middleware"
+	"lts/internal/app/quota"
+	"lts/internal/app/ratelimit"
 	"lts/internal/app/repository"
+	"lts/internal/app/sqlhook"
+	"lts/internal/app/tlscert"
+	"lts/internal/app/tracing"
+	"lts/migrations"
 
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-const (
-	postgres = "postgres"
-	sslMode  = "disable"
-)
+const postgres = "postgres"
 
 type App struct {
 	ctx    context.Context
+	stop   context.CancelFunc
 	cfg    config.Config
 	logger *zap.SugaredLogger
+
+	// workers - фоновые задачи, closers - ресурсы, которые закрываются после остановки сервера
+	workers sync.WaitGroup
+	closers []closer
+
+	// current - конфигурация с учётом перезагрузок, см. WatchConfig
+	current atomic.Pointer[config.Config]
+	loader  *config.Loader
+	level   zap.AtomicLevel
+	quota   *quota.Quota
+
+	// certs - сертификат HTTPS, nil без TLS
+	certs atomic.Pointer[tlscert.Reloader]
 }
 
+type closer struct {
+	name  string
+	close func() error
+}
+
+// New создаёт приложение. Завершение ctx (например, по сигналу) останавливает сервер
 func New(ctx context.Context, cfg config.Config, logger *zap.SugaredLogger) *App {
-	return &App{
+	ctx, stop := context.WithCancel(ctx)
+
+	a := &App{
 		ctx:    ctx,
+		stop:   stop,
 		cfg:    cfg,
 		logger: logger,
 	}
+	a.current.Store(&cfg)
+	return a
 }
 
 func (a *App) Run() error {
@@ -50,58 +95,508 @@ func (a *App) Run() error {
 }
 
 func (a *App) StartServer() error {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", a.cfg.PostgresConfig.Host, a.cfg.PostgresConfig.Port, a.cfg.PostgresConfig.User, a.cfg.PostgresConfig.Password, a.cfg.PostgresConfig.Name, sslMode)
+	shutdownTracing, err := tracing.Setup(a.ctx, a.cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("[tracing.Setup]: %w", err)
+	}
+	// Спаны отправляются последними, после остановки сервера и закрытия остальных ресурсов
+	a.onShutdown("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTP.ShutdownTimeout)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+	defer a.shutdown()
 
-	db, err := sqlx.Connect(postgres, dsn)
+	connector, err := pq.NewConnector(a.cfg.PostgresConfig.DSN())
 	if err != nil {
-		return fmt.Errorf("[sqlx.Connect]: %w", err)
+		return fmt.Errorf("[pq.NewConnector]: %w", err)
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(sqlhook.Connector(connector, observeQuery)), postgres)
+	db.SetMaxOpenConns(a.cfg.PostgresConfig.MaxOpenConns)
+	db.SetMaxIdleConns(a.cfg.PostgresConfig.MaxIdleConns)
+	db.SetConnMaxLifetime(a.cfg.PostgresConfig.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(a.cfg.PostgresConfig.ConnMaxIdleTime)
+	a.onShutdown("postgres", db.Close)
+
+	err = db.PingContext(a.ctx)
+	if err != nil {
+		return fmt.Errorf("[db.PingContext]: %w", err)
+	}
+
+	err = metrics.RegisterDB(db.DB)
+	if err != nil {
+		return fmt.Errorf("[metrics.RegisterDB]: %w", err)
 	}
 
 	travelRepo := repository.NewTravelRepo(db)
 	placeRepo := repository.NewPlaceRepositoryImpl(db)
 	expenseRepo := repository.NewExpensesRepo(db)
+	participantRepo := repository.NewParticipantRepo(db)
+	trackRepo := repository.NewTrackRepo(db)
+	legRepo := repository.NewLegRepo(db)
+	userRepo := repository.NewUserRepo(db)
+	tokenRepo := repository.NewTokenRepo(db)
+	memberRepo := repository.NewMemberRepo(db)
+	shareRepo := repository.NewShareRepo(db)
+	apiKeyRepo := repository.NewAPIKeyRepo(db)
+	auditRepo := repository.NewAuditRepo(db)
+	trashRepo := repository.NewTrashRepo(db)
+	revisionRepo := repository.NewRevisionRepo(db)
+	commentRepo := repository.NewCommentRepo(db)
+	healthRepo := repository.NewHealthRepo(db)
+
+	tokens, err := auth.NewTokenManager(a.cfg.AuthConfig)
+	if err != nil {
+		return fmt.Errorf("[auth.NewTokenManager]: %w", err)
+	}
 
-	travelHandler := handlers.NewTravelHandlerImpl(travelRepo, placeRepo, expenseRepo, a.logger)
+	if a.cfg.AuthConfig.LegacyOwner != "" {
+		adopted, err := userRepo.AdoptOrphanTravels(a.ctx, a.cfg.AuthConfig.LegacyOwner)
+		if err != nil {
+			return fmt.Errorf("[userRepo.AdoptOrphanTravels]: %w", err)
+		}
+		a.logger.Infow("travels without owner adopted", "owner", a.cfg.AuthConfig.LegacyOwner, "travels", adopted)
+	}
+
+	err = os.MkdirAll(a.cfg.Storage.Root, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("[os.MkdirAll]: %w", err)
+	}
+
+	expectedSchema, err := migrations.Latest()
+	if err != nil {
+		return fmt.Errorf("[migrations.Latest]: %w", err)
+	}
+
+	storageQuota := quota.New(a.cfg.Storage.Root, a.cfg.Quota)
+	a.quota = storageQuota
+
+	err = a.indexFiles(travelRepo, storageQuota)
+	if err != nil {
+		return err
+	}
+
+	travelHandler := handlers.NewTravelHandlerImpl(travelRepo, placeRepo, expenseRepo, trackRepo, legRepo, memberRepo, storageQuota)
 	th := handlers.TravelHandlerImplemented{TravelHandler: travelHandler}
 
-	placesHandler := handlers.NewPlaceHandlerImpl(placeRepo, travelRepo, a.logger)
+	placesHandler := handlers.NewPlaceHandlerImpl(placeRepo, travelRepo, memberRepo, storageQuota)
 	ph := handlers.PlaceHandlerImplemented{PlaceHandler: placesHandler}
 
-	expensesHandler := handlers.NewExpensesHandlerImpl(expenseRepo, placeRepo, a.logger)
+	expensesHandler := handlers.NewExpensesHandlerImpl(expenseRepo, placeRepo, legRepo, memberRepo, participantRepo)
 	eh := handlers.ExpensesHandlerImplemented{ExpensesHandler: expensesHandler}
 
+	participantsHandler := handlers.NewParticipantsHandlerImpl(participantRepo, travelRepo, placeRepo, expenseRepo, legRepo)
+	pth := handlers.ParticipantsHandlerImplemented{ParticipantsHandler: participantsHandler}
+
+	legHandler := handlers.NewLegHandlerImpl(legRepo, travelRepo, placeRepo, expenseRepo)
+	lh := handlers.LegHandlerImplemented{LegHandler: legHandler}
+
+	memberHandler := handlers.NewMemberHandlerImpl(memberRepo)
+	mh := handlers.MemberHandlerImplemented{MemberHandler: memberHandler}
+
+	shareHandler := handlers.NewShareHandlerImpl(shareRepo, travelRepo, placeRepo, expenseRepo, legRepo)
+	sh := handlers.ShareHandlerImplemented{ShareHandler: shareHandler}
+
+	apiKeyHandler := handlers.NewAPIKeyHandlerImpl(apiKeyRepo)
+	kh := handlers.APIKeyHandlerImplemented{APIKeyHandler: apiKeyHandler}
+
+	auditHandler := handlers.NewAuditHandlerImpl(auditRepo)
+	adh := handlers.AuditHandlerImplemented{AuditHandler: auditHandler}
+
+	trashHandler := handlers.NewTrashHandlerImpl(trashRepo, a.cfg.Trash.Retention)
+	trh := handlers.TrashHandlerImplemented{TrashHandler: trashHandler}
+
+	revisionHandler := handlers.NewRevisionHandlerImpl(revisionRepo)
+	rvh := handlers.RevisionHandlerImplemented{RevisionHandler: revisionHandler}
+
+	commentHandler := handlers.NewCommentHandlerImpl(commentRepo)
+	ch := handlers.CommentHandlerImplemented{CommentHandler: commentHandler}
+
+	healthHandler := handlers.NewHealthHandlerImpl(healthRepo, a.cfg.Storage.Root, expectedSchema)
+	hh := handlers.HealthHandlerImplemented{HealthHandler: healthHandler}
+
+	authHandler := handlers.NewAuthHandlerImpl(userRepo, tokenRepo, tokens, a.cfg.AuthConfig.RefreshTTL)
+	ah := handlers.AuthHandlerImplemented{AuthHandler: authHandler}
+
+	a.background(func() { a.purgeAudit(auditRepo) })
+	a.background(func() { a.purgeTrash(trashRepo) })
+	if a.loader != nil {
+		a.background(a.watchConfig)
+	}
+
 	r := mux.NewRouter()
-	r.Use(middleware.CORSMiddleware)
+	r.Use(otelmux.Middleware(a.cfg.Tracing.ServiceName), middleware.AccessRoute)
+	r.Use(middleware.Metrics, middleware.CORS(func() config.CORS { return a.current.Load().CORS }))
+
+	limitIP, limitUser, err := a.rateLimits()
+	if err != nil {
+		return err
+	}
+
+	// Лимит по IP - только для API и документации: пробы оркестратора и сбор метрик не должны получать 429
+	public := r.PathPrefix("/api").Subrouter()
+	public.Use(limitIP)
+
+	public.HandleFunc("/auth/register", ah.Register).Methods("POST", "OPTIONS")
+	public.HandleFunc("/auth/login", ah.Login).Methods("POST", "OPTIONS")
+	public.HandleFunc("/auth/refresh", ah.Refresh).Methods("POST", "OPTIONS")
+	public.HandleFunc("/auth/logout", ah.Logout).Methods("POST", "OPTIONS")
 
-	api := r.PathPrefix("/api").Subrouter()
+	public.HandleFunc("/shared/{token}", sh.GetSharedTravel).Methods("GET", "OPTIONS")
+
+	// Все остальные маршруты API доступны только аутентифицированным пользователям.
+	// API-ключу для загрузки файлов нужно право media, для остальных маршрутов - read или write по методу
+	authenticate := middleware.AuthMiddleware(tokens, apiKeyRepo)
+
+	media := public.NewRoute().Subrouter()
+	media.Use(authenticate, limitUser, middleware.RequireScope(ds.ScopeMedia))
+
+	media.HandleFunc("/travel/preview/{uuid}", th.SetTravelPreview).Methods("PUT", "OPTIONS")
+	media.HandleFunc("/travel/{uuid}/import.gpx", th.ImportGPX).Methods("POST", "OPTIONS")
+	media.HandleFunc("/place/{travel_uuid}/{place_uuid}", ph.SetPreview).Methods("PUT", "OPTIONS")
+	media.HandleFunc("/place/images/{travel_uuid}/{place_uuid}", ph.SetImages).Methods("PUT", "OPTIONS")
+
+	api := public.NewRoute().Subrouter()
+	api.Use(authenticate, limitUser, middleware.MethodScopes)
+
+	api.HandleFunc("/auth/me", ah.Me).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/keys", kh.CreateAPIKey).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/keys", kh.GetAPIKeys).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/keys/{uuid}", kh.RevokeAPIKey).Methods("DELETE", "OPTIONS")
 
 	api.HandleFunc("/travel", th.CreateTravel).Methods("POST", "OPTIONS")
 	api.HandleFunc("/travel/{uuid}", th.GetTravel).Methods("GET", "OPTIONS")
-	api.HandleFunc("/travel/preview/{uuid}", th.SetTravelPreview).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/travel/{uuid}", th.UpdateTravel).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/travel/{uuid}", th.DeleteTravel).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/travel", th.GetAllTravels).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/restore", trh.RestoreTravel).Methods("POST", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/trash", trh.GetTrashedPlaces).Methods("GET", "OPTIONS")
+	api.HandleFunc("/trash", trh.GetTrashedTravels).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/revisions", rvh.GetTravelRevisions).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/revisions/diff", rvh.GetTravelRevisionDiff).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/revisions/{revision_id}/restore", rvh.RestoreTravelRevision).Methods("POST", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/comments", ch.GetTravelComments).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/comments", ch.CreateTravelComment).Methods("POST", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/geojson", th.GetTravelGeoJSON).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/export.gpx", th.ExportGPX).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/export.kml", th.ExportKML).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/tracks", th.GetTracks).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/history", adh.GetHistory).Methods("GET", "OPTIONS")
+
+	api.HandleFunc("/travel/{uuid}/members", mh.GetMembers).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/invites", mh.CreateInvite).Methods("POST", "OPTIONS")
+	api.HandleFunc("/travel/{travel_uuid}/members/{user_uuid}", mh.UpdateMemberRole).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/travel/{travel_uuid}/members/{user_uuid}", mh.DeleteMember).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/invites/accept", mh.AcceptInvite).Methods("POST", "OPTIONS")
+
+	api.HandleFunc("/travel/{uuid}/shares", sh.CreateShare).Methods("POST", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/shares", sh.GetShares).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{travel_uuid}/shares/{share_uuid}", sh.RevokeShare).Methods("DELETE", "OPTIONS")
+
+	api.HandleFunc("/travel/{uuid}/participants", pth.CreateParticipant).Methods("POST", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/participants", pth.GetParticipants).Methods("GET", "OPTIONS")
+	api.HandleFunc("/travel/{travel_uuid}/participants/{participant_uuid}", pth.DeleteParticipant).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/balances", pth.GetBalances).Methods("GET", "OPTIONS")
 
 	api.HandleFunc("/place/{travel_uuid}", ph.CreatePlace).Methods("POST", "OPTIONS")
-	api.HandleFunc("/place/{travel_uuid}/{place_uuid}", ph.SetPreview).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/place/{travel_uuid}/{place_uuid}", ph.DeletePlace).Methods("DELETE", "OPTIONS")
-	api.HandleFunc("/place/images/{travel_uuid}/{place_uuid}", ph.SetImages).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/place/{uuid}", ph.UpdatePlace).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/place/{travel_uuid}/{place_uuid}/restore", trh.RestorePlace).Methods("POST", "OPTIONS")
+	api.HandleFunc("/place/{uuid}/revisions", rvh.GetPlaceRevisions).Methods("GET", "OPTIONS")
+	api.HandleFunc("/place/{uuid}/revisions/diff", rvh.GetPlaceRevisionDiff).Methods("GET", "OPTIONS")
+	api.HandleFunc("/place/{uuid}/revisions/{revision_id}/restore", rvh.RestorePlaceRevision).Methods("POST", "OPTIONS")
+	api.HandleFunc("/place/{uuid}/comments", ch.GetPlaceComments).Methods("GET", "OPTIONS")
+	api.HandleFunc("/place/{uuid}/comments", ch.CreatePlaceComment).Methods("POST", "OPTIONS")
+
+	api.HandleFunc("/comments/{uuid}", ch.UpdateComment).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/comments/{uuid}", ch.DeleteComment).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/comments/{uuid}/reactions/{emoji}", ch.AddReaction).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/comments/{uuid}/reactions/{emoji}", ch.DeleteReaction).Methods("DELETE", "OPTIONS")
+
+	api.HandleFunc("/travel/{uuid}/legs", lh.CreateLeg).Methods("POST", "OPTIONS")
+	api.HandleFunc("/travel/{uuid}/legs", lh.GetLegs).Methods("GET", "OPTIONS")
+	api.HandleFunc("/leg/{uuid}", lh.UpdateLeg).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/leg/{uuid}", lh.DeleteLeg).Methods("DELETE", "OPTIONS")
 
 	api.HandleFunc("/expenses/{place_uuid}", eh.CreateExpense).Methods("POST", "OPTIONS")
+	api.HandleFunc("/expenses/leg/{leg_uuid}", eh.CreateLegExpense).Methods("POST", "OPTIONS")
 	api.HandleFunc("/expenses/{uuid}", eh.GetExpense).Methods("GET", "OPTIONS")
 	api.HandleFunc("/expenses/{uuid}", eh.UpdateExpense).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/expenses/{uuid}", eh.DeleteExpense).Methods("DELETE", "OPTIONS")
 
-	r.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler).Methods("GET", "OPTIONS")
+	r.PathPrefix("/swagger").Handler(limitIP(httpSwagger.WrapHandler)).Methods("GET", "OPTIONS")
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+	r.HandleFunc("/healthz", hh.Healthz).Methods("GET")
+	r.HandleFunc("/readyz", hh.Readyz).Methods("GET")
+	r.HandleFunc("/version", hh.Version).Methods("GET")
+
+	server := &http.Server{
+		Addr:              a.cfg.HTTP.Addr,
+		Handler:           middleware.RequestID(middleware.AccessLog(a.logger)(r)),
+		ReadTimeout:       a.cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: a.cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      a.cfg.HTTP.WriteTimeout,
+		IdleTimeout:       a.cfg.HTTP.IdleTimeout,
+		ErrorLog:          zap.NewStdLog(a.logger.Desugar()),
+	}
+
+	servers := []*http.Server{server}
+	if a.cfg.TLS.Enabled {
+		server.TLSConfig, err = a.tlsConfig()
+		if err != nil {
+			return fmt.Errorf("[a.tlsConfig]: %w", err)
+		}
+		if a.cfg.TLS.RedirectAddr != "" {
+			servers = append(servers, a.redirectServer())
+		}
+	}
+
+	listeners := make([]net.Listener, len(servers))
+	for i, srv := range servers {
+		listeners[i], err = net.Listen("tcp", srv.Addr)
+		if err != nil {
+			for _, listener := range listeners[:i] {
+				_ = listener.Close()
+			}
+			return fmt.Errorf("[net.Listen]: %w", err)
+		}
+	}
+
+	serveErr := make(chan error, len(servers))
+	go func() {
+		if a.cfg.TLS.Enabled {
+			// Сертификат отдаёт server.TLSConfig.GetCertificate
+			serveErr <- server.ServeTLS(listeners[0], "", "")
+			return
+		}
+		serveErr <- server.Serve(listeners[0])
+	}()
+	a.logger.Infow("server started", "addr", listeners[0].Addr().String(), "tls", a.cfg.TLS.Enabled)
+
+	if len(servers) > 1 {
+		go func() { serveErr <- servers[1].Serve(listeners[1]) }()
+		a.logger.Infow("redirecting http to https", "addr", listeners[1].Addr().String())
+	}
+
+	select {
+	case err = <-serveErr:
+		return fmt.Errorf("[server.Serve]: %w", err)
+	case <-a.ctx.Done():
+	}
+
+	// Новые соединения больше не принимаются, начатые запросы дорабатывают не дольше shutdown_timeout
+	a.logger.Infow("shutting down the server", "timeout", a.cfg.HTTP.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	for _, srv := range servers {
+		err = srv.Shutdown(ctx)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("[server.Shutdown]: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// background запускает фоновую задачу. Задача должна завершиться по a.ctx, при остановке её дожидаются
+func (a *App) background(fn func()) {
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		fn()
+	}()
+}
+
+// onShutdown регистрирует ресурс, который закроется при остановке приложения
+func (a *App) onShutdown(name string, close func() error) {
+	a.closers = append(a.closers, closer{name: name, close: close})
+}
+
+// shutdown останавливает фоновые задачи и закрывает ресурсы в порядке, обратном открытию
+func (a *App) shutdown() {
+	a.stop()
+	a.workers.Wait()
 
-	router := middleware.LogMiddleware(a.logger, r)
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		c := a.closers[i]
 
-	log.Println("server started")
-	err = http.ListenAndServe(":8000", router)
+		err := c.close()
+		if err != nil {
+			a.logger.Errorw("[app.shutdown]", "resource", c.name, "error", err)
+		}
+	}
+	a.closers = nil
+}
+
+// rateLimits собирает middleware ограничения частоты запросов по адресу клиента и по пользователю
+func (a *App) rateLimits() (mux.MiddlewareFunc, mux.MiddlewareFunc, error) {
+	cfg := a.cfg.RateLimit
+	if !cfg.Enabled {
+		pass := func(next http.Handler) http.Handler { return next }
+		return pass, pass, nil
+	}
+
+	var limiter ratelimit.Limiter
+
+	switch cfg.Backend {
+	case "", "memory":
+		limiter = ratelimit.NewMemory()
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr, Password: cfg.Redis.Password, DB: cfg.Redis.DB})
+
+		a.onShutdown("redis", client.Close)
+
+		err := client.Ping(a.ctx).Err()
+		if err != nil {
+			return nil, nil, fmt.Errorf("[redis.Ping]: %w", err)
+		}
+		limiter = ratelimit.NewRedis(client)
+	default:
+		return nil, nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+	}
+
+	perIP := func() ratelimit.Limit { return ratelimit.Limit(a.current.Load().RateLimit.PerIP) }
+	perUser := func() ratelimit.Limit { return ratelimit.Limit(a.current.Load().RateLimit.PerUser) }
+
+	limitIP := middleware.RateLimit(limiter, perIP, middleware.ClientIP)
+	limitUser := middleware.RateLimit(limiter, perUser, middleware.PrincipalKey)
+	return limitIP, limitUser, nil
+}
+
+// purgeInterval - период удаления устаревших записей журнала изменений и очистки корзины
+const purgeInterval = time.Hour
+
+// purgeAudit удаляет записи журнала старше audit.retention
+func (a *App) purgeAudit(auditRepo repository.AuditRepository) {
+	retention := a.cfg.Audit.Retention
+	if retention <= 0 {
+		return
+	}
+
+	a.periodically(purgeInterval, func() {
+		n, err := auditRepo.Purge(a.ctx, retention)
+		if err != nil {
+			a.logger.Errorw("[auditRepo.Purge]", "error", err)
+		} else if n > 0 {
+			a.logger.Infow("audit log purged", "deleted", n)
+		}
+	})
+}
+
+// purgeTrash окончательно удаляет путешествия и места, пролежавшие в корзине дольше trash.retention.
+// Файлы удаляются после строк в БД: если удалить их не получится, останутся только лишние файлы
+func (a *App) purgeTrash(trashRepo repository.TrashRepository) {
+	retention := a.cfg.Trash.Retention
+	if retention <= 0 {
+		return
+	}
+
+	a.periodically(purgeInterval, func() {
+		before := time.Now().Add(-retention)
+
+		travels, err := trashRepo.PurgeTravels(a.ctx, before)
+		if err != nil {
+			a.logger.Errorw("[trashRepo.PurgeTravels]", "error", err)
+		}
+		for _, id := range travels {
+			a.removeImages(filepath.Join(a.cfg.Storage.Root, id.String()))
+		}
+
+		places, err := trashRepo.PurgePlaces(a.ctx, before)
+		if err != nil {
+			a.logger.Errorw("[trashRepo.PurgePlaces]", "error", err)
+		}
+		for _, place := range places {
+			a.removeImages(filepath.Join(a.cfg.Storage.Root, place.TravelID.String(), "places", place.ID.String()))
+		}
+
+		if len(travels) > 0 || len(places) > 0 {
+			a.logger.Infow("trash purged", "travels", len(travels), "places", len(places))
+		}
+	})
+}
+
+// indexFiles учитывает в квоте файлы, записанные до появления учёта в БД.
+// Хранилище обходится, только пока не учтено ни одного файла
+func (a *App) indexFiles(travelRepo repository.TravelRepository, storageQuota *quota.Quota) error {
+	indexed, err := travelRepo.HasFiles(a.ctx)
 	if err != nil {
-		a.logger.Fatal()
+		return fmt.Errorf("[travelRepo.HasFiles]: %w", err)
+	}
+	if indexed {
+		return nil
 	}
 
+	files, err := storageQuota.Files()
+	if err != nil {
+		return fmt.Errorf("[storageQuota.Files]: %w", err)
+	}
+
+	added, err := travelRepo.IndexFiles(a.ctx, files)
+	if err != nil {
+		return fmt.Errorf("[travelRepo.IndexFiles]: %w", err)
+	}
+	if added > 0 {
+		a.logger.Infow("storage files indexed", "files", added)
+	}
 	return nil
 }
+
+func (a *App) removeImages(path string) {
+	_, span := tracing.Start(a.ctx, "storage.delete", trace.WithAttributes(attribute.String("storage.path", path)))
+	err := os.RemoveAll(path)
+	tracing.End(span, err)
+	if err != nil {
+		a.logger.Errorw("[os.RemoveAll]", "path", path, "error", err)
+	}
+}
+
+// periodically вызывает fn сразу и затем каждые interval, пока не завершится контекст приложения
+func (a *App) periodically(interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn()
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// repositoryPkg - пакет репозиториев, по методам которых размечаются запросы к БД
+var repositoryPkg = reflect.TypeOf(repository.TravelRepositoryImpl{}).PkgPath()
+
+// observeQuery записывает длительность запроса к БД с именем метода репозитория, из которого он выполнен,
+// и спан с этим именем и текстом запроса
+func observeQuery(ctx context.Context, query string) func(error) {
+	start := time.Now()
+
+	repo, method := sqlhook.Caller(repositoryPkg)
+	if repo == "" {
+		repo, method = "unknown", "unknown"
+	}
+	repo = strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(repo, "Impl"), "Repository"))
+
+	_, span := tracing.Start(ctx, repo+"."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(method),
+		semconv.DBQueryText(query),
+	))
+
+	return func(err error) {
+		tracing.End(span, err)
+
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		metrics.DBQueryDuration.WithLabelValues(repo, method, result).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"lts/internal/app/config"
+	"lts/internal/app/metrics"
+)
+
+// WatchConfig включает перезагрузку конфигурации при изменении файла и по SIGHUP.
+// Без перезапуска применяются уровень логов, политика CORS, лимиты запросов и загрузок;
+// level - уровень логгера приложения. Вызывается до Run
+func (a *App) WatchConfig(loader *config.Loader, level zap.AtomicLevel) {
+	a.loader = loader
+	a.level = level
+}
+
+// watchConfig ждёт изменений файла конфигурации и SIGHUP, пока не завершится контекст приложения.
+// Несколько изменений подряд приводят к одной перезагрузке
+func (a *App) watchConfig() {
+	changed := make(chan struct{}, 1)
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		err := a.loader.Watch(a.ctx, func() {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		if err != nil {
+			a.logger.Errorw("[loader.Watch]: config file changes are not tracked, use SIGHUP", "error", err)
+		}
+	}()
+	// Слежение за файлом завершается вместе с контекстом приложения, его дожидаемся при остановке
+	defer func() { <-watched }()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-hup:
+			a.reloadConfig("sighup")
+			// По SIGHUP перечитывается и сертификат, например из deploy-hook certbot
+			if certs := a.certs.Load(); certs != nil {
+				a.certReloaded(certs.Reload())
+			}
+		case <-changed:
+			a.reloadConfig("file")
+		}
+	}
+}
+
+// reloadConfig перечитывает конфигурацию и применяет то, что можно изменить без перезапуска.
+// Конфигурация с ошибками отклоняется целиком, прежние настройки продолжают действовать
+func (a *App) reloadConfig(trigger string) {
+	cfg, err := a.loader.Load()
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("failure").Inc()
+		a.logger.Errorw("config reload rejected", "trigger", trigger, "path", a.loader.Path(), "error", err)
+		return
+	}
+
+	current := *a.current.Load()
+
+	next := current
+	applyReloadable(&next, cfg)
+
+	if restart := changedSections(next, cfg); len(restart) > 0 {
+		a.logger.Warnw("config changes require a restart and are not applied", "sections", restart)
+	}
+
+	// Уровень уже проверен в Validate
+	level, _ := zapcore.ParseLevel(next.Log.Level)
+	a.level.SetLevel(level)
+	a.quota.SetLimits(next.Quota)
+	a.current.Store(&next)
+
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	metrics.ConfigLastReload.SetToCurrentTime()
+	a.logger.Infow("config reloaded", "trigger", trigger, "changed", changedSections(current, next))
+}
+
+// applyReloadable переносит в dst настройки, которые применяются без перезапуска
+func applyReloadable(dst *config.Config, src config.Config) {
+	dst.Log.Level = src.Log.Level
+	dst.CORS = src.CORS
+	dst.RateLimit.PerIP = src.RateLimit.PerIP
+	dst.RateLimit.PerUser = src.RateLimit.PerUser
+	dst.Quota = src.Quota
+}
+
+// changedSections - секции конфигурации верхнего уровня, которые отличаются в old и cur
+func changedSections(old, cur config.Config) []string {
+	var sections []string
+
+	va, vb := reflect.ValueOf(old), reflect.ValueOf(cur)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			sections = append(sections, va.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return sections
+}
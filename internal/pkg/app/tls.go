@@ -0,0 +1,77 @@
+package app
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"lts/internal/app/tlscert"
+)
+
+// tlsConfig загружает сертификат и запускает слежение за его файлами. HTTP/2 включается
+// в ServeTLS автоматически, NextProtos только фиксирует порядок протоколов
+func (a *App) tlsConfig() (*tls.Config, error) {
+	certs, err := tlscert.NewReloader(a.cfg.TLS.CertFile, a.cfg.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	a.certs.Store(certs)
+
+	a.background(func() {
+		err := certs.Watch(a.ctx, a.certReloaded)
+		if err != nil {
+			a.logger.Errorw("[certs.Watch]: tls certificate will not be reloaded on file change", "error", err)
+		}
+	})
+
+	minVersion := uint16(tls.VersionTLS12)
+	if a.cfg.TLS.MinVersion == "1.3" {
+		minVersion = tls.VersionTLS13
+	}
+
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: certs.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// certReloaded пишет в лог результат перечитывания сертификата
+func (a *App) certReloaded(err error) {
+	if err != nil {
+		a.logger.Warnw("tls certificate reload failed, the previous one stays in use", "error", err)
+		return
+	}
+	a.logger.Infow("tls certificate reloaded", "cert_file", a.cfg.TLS.CertFile)
+}
+
+// redirectServer отвечает на запросы по HTTP постоянным перенаправлением на тот же адрес по HTTPS.
+// 308 сохраняет метод и тело, поэтому перенаправляются и POST, и PUT
+func (a *App) redirectServer() *http.Server {
+	_, httpsPort, _ := net.SplitHostPort(a.cfg.HTTP.Addr)
+
+	return &http.Server{
+		Addr: a.cfg.TLS.RedirectAddr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+			}
+			if httpsPort != "" && httpsPort != "443" {
+				host = net.JoinHostPort(host, httpsPort)
+			} else if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		}),
+		ReadTimeout:       a.cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: a.cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      a.cfg.HTTP.WriteTimeout,
+		IdleTimeout:       a.cfg.HTTP.IdleTimeout,
+		ErrorLog:          zap.NewStdLog(a.logger.Desugar()),
+	}
+}
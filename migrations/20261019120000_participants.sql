@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+create table participants
+(
+    id        uuid NOT NULL primary key,
+    travel_id uuid NOT NULL references travel (id) on delete cascade,
+    name      text
+);
+
+alter table expenses
+    add column payer      uuid references participants (id) on delete restrict,
+    add column split_type text NOT NULL default 'equal';
+
+create table expense_shares
+(
+    expense_id     uuid    NOT NULL references expenses (id) on delete cascade,
+    participant_id uuid    NOT NULL references participants (id) on delete restrict,
+    value          integer NOT NULL default 0,
+    primary key (expense_id, participant_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE expense_shares;
+ALTER TABLE expenses DROP COLUMN split_type, DROP COLUMN payer;
+DROP TABLE participants;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table places
+    add column latitude     double precision,
+    add column longitude    double precision,
+    add column address      text,
+    add column country_code char(2);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE places
+    DROP COLUMN country_code,
+    DROP COLUMN address,
+    DROP COLUMN longitude,
+    DROP COLUMN latitude;
+-- +goose StatementEnd
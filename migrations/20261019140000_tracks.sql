@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+create table tracks
+(
+    id             uuid NOT NULL primary key,
+    travel_id      uuid NOT NULL references travel (id) on delete cascade,
+    name           text,
+    segments       jsonb NOT NULL,
+    distance       double precision NOT NULL default 0,
+    elevation_gain double precision NOT NULL default 0,
+    started_at     timestamptz,
+    finished_at    timestamptz
+);
+
+create index tracks_travel_id_idx on tracks (travel_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE tracks;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+create table legs
+(
+    id           uuid NOT NULL primary key,
+    travel_id    uuid NOT NULL references travel (id) on delete cascade,
+    from_place   uuid NOT NULL references places (id) on delete cascade,
+    to_place     uuid NOT NULL references places (id) on delete cascade,
+    mode         text NOT NULL,
+    carrier      text,
+    departure_at timestamptz,
+    departure_tz text,
+    arrival_at   timestamptz,
+    arrival_tz   text,
+    booking_ref  text,
+    distance     double precision,
+    expenses     uuid references expenses (id) on delete set null
+);
+
+create index legs_travel_id_idx on legs (travel_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE legs;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+create table users
+(
+    id            uuid NOT NULL primary key,
+    email         text NOT NULL unique,
+    name          text,
+    password_hash text NOT NULL,
+    created_at    timestamptz NOT NULL default now()
+);
+
+create table sessions
+(
+    token_hash text NOT NULL primary key,
+    user_id    uuid NOT NULL references users (id) on delete cascade,
+    created_at timestamptz NOT NULL default now(),
+    expires_at timestamptz NOT NULL
+);
+
+create index sessions_user_id_idx on sessions (user_id);
+
+-- Путешествия, созданные до появления пользователей, остаются без владельца
+-- и не видны никому, пока им не назначат owner_id вручную
+alter table travel
+    add column owner_id uuid references users (id) on delete cascade;
+
+create index travel_owner_id_idx on travel (owner_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE travel DROP COLUMN owner_id;
+DROP TABLE sessions;
+DROP TABLE users;
+-- +goose StatementEnd
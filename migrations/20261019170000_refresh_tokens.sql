@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+DROP TABLE sessions;
+
+-- family_id объединяет цепочку ротаций одного входа: повторное использование
+-- уже заменённого токена отзывает всю цепочку
+create table refresh_tokens
+(
+    id          uuid NOT NULL primary key,
+    token_hash  text NOT NULL unique,
+    user_id     uuid NOT NULL references users (id) on delete cascade,
+    family_id   uuid NOT NULL,
+    created_at  timestamptz NOT NULL default now(),
+    expires_at  timestamptz NOT NULL,
+    revoked_at  timestamptz,
+    replaced_by uuid
+);
+
+create index refresh_tokens_user_id_idx on refresh_tokens (user_id);
+create index refresh_tokens_family_id_idx on refresh_tokens (family_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE refresh_tokens;
+
+create table sessions
+(
+    token_hash text NOT NULL primary key,
+    user_id    uuid NOT NULL references users (id) on delete cascade,
+    created_at timestamptz NOT NULL default now(),
+    expires_at timestamptz NOT NULL
+);
+
+create index sessions_user_id_idx on sessions (user_id);
+-- +goose StatementEnd
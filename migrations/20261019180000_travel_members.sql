@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+create table travel_members
+(
+    travel_id  uuid NOT NULL references travel (id) on delete cascade,
+    user_id    uuid NOT NULL references users (id) on delete cascade,
+    role       text NOT NULL check (role in ('owner', 'editor', 'viewer')),
+    created_at timestamptz NOT NULL default now(),
+    primary key (travel_id, user_id)
+);
+
+create index travel_members_user_id_idx on travel_members (user_id);
+
+-- Доступ к путешествиям теперь определяется только участием, владельцы становятся участниками с ролью owner
+insert into travel_members (travel_id, user_id, role)
+select id, owner_id, 'owner'
+from travel
+where owner_id is not null;
+
+create table travel_invites
+(
+    id          uuid NOT NULL primary key,
+    travel_id   uuid NOT NULL references travel (id) on delete cascade,
+    email       text NOT NULL,
+    role        text NOT NULL check (role in ('owner', 'editor', 'viewer')),
+    token_hash  text NOT NULL unique,
+    invited_by  uuid references users (id) on delete set null,
+    created_at  timestamptz NOT NULL default now(),
+    expires_at  timestamptz NOT NULL,
+    accepted_at timestamptz
+);
+
+create index travel_invites_travel_id_idx on travel_invites (travel_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE travel_invites;
+DROP TABLE travel_members;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+create table travel_shares
+(
+    id             uuid NOT NULL primary key,
+    travel_id      uuid NOT NULL references travel (id) on delete cascade,
+    token_hash     text NOT NULL unique,
+    hide_expenses  boolean NOT NULL default false,
+    strip_location boolean NOT NULL default false,
+    created_by     uuid references users (id) on delete set null,
+    created_at     timestamptz NOT NULL default now(),
+    expires_at     timestamptz,
+    revoked_at     timestamptz
+);
+
+create index travel_shares_travel_id_idx on travel_shares (travel_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE travel_shares;
+-- +goose StatementEnd
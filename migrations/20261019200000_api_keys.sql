@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+create table api_keys
+(
+    id           uuid NOT NULL primary key,
+    user_id      uuid NOT NULL references users (id) on delete cascade,
+    name         text NOT NULL,
+    prefix       text NOT NULL,
+    key_hash     text NOT NULL unique,
+    scopes       text[] NOT NULL,
+    created_at   timestamptz NOT NULL default now(),
+    expires_at   timestamptz,
+    last_used_at timestamptz,
+    revoked_at   timestamptz
+);
+
+create index api_keys_user_id_idx on api_keys (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE api_keys;
+-- +goose StatementEnd
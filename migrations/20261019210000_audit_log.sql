@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+create table audit_log
+(
+    id          bigserial primary key,
+    travel_id   uuid,
+    actor_id    uuid references users (id) on delete set null,
+    api_key_id  uuid,
+    action      text NOT NULL check (action in ('create', 'update', 'delete')),
+    entity_type text NOT NULL,
+    entity_id   uuid NOT NULL,
+    before      jsonb,
+    after       jsonb,
+    request_id  text,
+    created_at  timestamptz NOT NULL default now()
+);
+
+create index audit_log_travel_id_idx on audit_log (travel_id, id);
+create index audit_log_created_at_idx on audit_log (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE audit_log;
+-- +goose StatementEnd
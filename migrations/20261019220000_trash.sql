@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table travel
+    add column deleted_at timestamptz,
+    add column deleted_by uuid references users (id) on delete set null;
+
+alter table places
+    add column deleted_at timestamptz,
+    add column deleted_by uuid references users (id) on delete set null;
+
+create index travel_deleted_at_idx on travel (deleted_at) where deleted_at is not null;
+create index places_deleted_at_idx on places (deleted_at) where deleted_at is not null;
+
+alter table audit_log
+    drop constraint audit_log_action_check,
+    add constraint audit_log_action_check check (action in ('create', 'update', 'delete', 'restore'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE audit_log SET action = 'update' WHERE action = 'restore';
+ALTER TABLE audit_log
+    DROP CONSTRAINT audit_log_action_check,
+    ADD CONSTRAINT audit_log_action_check CHECK (action IN ('create', 'update', 'delete'));
+ALTER TABLE places DROP COLUMN deleted_at, DROP COLUMN deleted_by;
+ALTER TABLE travel DROP COLUMN deleted_at, DROP COLUMN deleted_by;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+create table revisions
+(
+    id          bigserial primary key,
+    entity_type text NOT NULL check (entity_type in ('travel', 'place')),
+    entity_id   uuid NOT NULL,
+    author_id   uuid references users (id) on delete set null,
+    body        text NOT NULL,
+    diff        text NOT NULL,
+    created_at  timestamptz NOT NULL default now()
+);
+
+create index revisions_entity_idx on revisions (entity_type, entity_id, id);
+
+-- Текущие тексты становятся первыми ревизиями без автора и без разницы
+insert into revisions (entity_type, entity_id, body, diff)
+select 'travel', id, description, '' from travel where coalesce(description, '') <> '';
+
+insert into revisions (entity_type, entity_id, body, diff)
+select 'place', id, story, '' from places where coalesce(story, '') <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE revisions;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+create table comments
+(
+    id         uuid NOT NULL primary key,
+    travel_id  uuid NOT NULL references travel (id) on delete cascade,
+    place_id   uuid references places (id) on delete cascade,
+    parent_id  uuid references comments (id) on delete cascade,
+    author_id  uuid references users (id) on delete set null,
+    body       text NOT NULL,
+    created_at timestamptz NOT NULL default now(),
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+create index comments_travel_idx on comments (travel_id, place_id, created_at, id) where parent_id is null;
+create index comments_parent_id_idx on comments (parent_id);
+
+create table comment_reactions
+(
+    comment_id uuid NOT NULL references comments (id) on delete cascade,
+    user_id    uuid NOT NULL references users (id) on delete cascade,
+    emoji      text NOT NULL,
+    created_at timestamptz NOT NULL default now(),
+    primary key (comment_id, user_id, emoji)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE comment_reactions;
+DROP TABLE comments;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+create table storage_files
+(
+    path      text   NOT NULL primary key,
+    travel_id uuid   NOT NULL references travel (id) on delete cascade,
+    place_id  uuid references places (id) on delete cascade,
+    size      bigint NOT NULL
+);
+
+create index storage_files_travel_id_idx on storage_files (travel_id);
+create index storage_files_place_id_idx on storage_files (place_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE storage_files;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+// Package migrations встраивает SQL-миграции в бинарник, чтобы сервис знал, какую версию схемы он ожидает.
+// goose пропускает .go файлы без номера версии в имени, поэтому этот файл миграцией не считается
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Latest - версия последней миграции, число перед _ в имени файла
+func Latest() (int64, error) {
+	files, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		return 0, fmt.Errorf("[fs.Glob]: %w", err)
+	}
+
+	var latest int64
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(file, "_")
+		if !ok {
+			return 0, fmt.Errorf("migration %s has no version prefix", file)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: %w", file, err)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
@@ -8,7 +8,7 @@ host="$1"
 shift
 cmd="$@"
 
-until PGPASSWORD=$DB_PASS psql -d $DB_NAME -h "$DB_HOST" -U $DB_USER -c '\q'; do
+until PGPASSWORD=$LTS_POSTGRES_PASS psql -d $LTS_POSTGRES_NAME -h "$LTS_POSTGRES_HOST" -U $LTS_POSTGRES_USER -c '\q'; do
   >&2 echo "Postgres is unavailable - sleeping"
   sleep 1
 done